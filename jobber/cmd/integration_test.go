@@ -5,6 +5,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"flag"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -14,21 +15,37 @@ import (
 	log "github.com/sirupsen/logrus"
 	"bou.ke/monkey"
 
+	"github.com/fedorkolmykow/avitojob/pkg/auth"
+	"github.com/fedorkolmykow/avitojob/pkg/config"
 	"github.com/fedorkolmykow/avitojob/pkg/httpServer"
 	"github.com/fedorkolmykow/avitojob/pkg/postgres"
+	"github.com/fedorkolmykow/avitojob/pkg/ratelimit"
 	"github.com/fedorkolmykow/avitojob/pkg/redis"
 	"github.com/fedorkolmykow/avitojob/pkg/service"
 
 )
 
 func start(t *testing.T) *http.Server{
-
-	redCon := redis.NewDb()
-	dbCon := postgres.NewDbClient()
-	swc := service.NewService(dbCon, redCon)
-	router := httpServer.NewHTTPServer(swc)
+	cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err != nil{
+		t.Fatal(err)
+	}
+	err = migrateUp(cfg.Postgres)
+	if err != nil{
+		t.Fatal(err)
+	}
+	redCon := redis.NewDb(cfg.Redis)
+	dbCon := postgres.NewDbClient(cfg.Postgres)
+	swc := service.NewService(dbCon, redCon, service.NewFixedRateProvider(map[string]float64{"USD": 0.0125}))
+	authn, err := auth.New(cfg.Auth)
+	if err != nil{
+		t.Fatal(err)
+	}
+	router := httpServer.NewHTTPServer(swc, httpServer.Timeouts{Default: 10*time.Second},
+		httpServer.NewHealth(time.Second, map[string]httpServer.Pinger{"postgres": dbCon, "redis": redCon}), nil, authn,
+		ratelimit.New(redCon, cfg.RateLimit))
 	srv := &http.Server{
-		Addr:    os.Getenv("HTTP_PORT"),
+		Addr:    cfg.HTTP.Port,
 		Handler: router,
 	}
 	go func() {
@@ -43,6 +60,7 @@ func start(t *testing.T) *http.Server{
 
 type testCase struct{
 	RespExpData string
+	Header      map[string]string
 	ReqData		[]byte
 	Url         string
 	Method      string
@@ -59,35 +77,128 @@ func Test_All(t *testing.T){
 
 	cases := []testCase{
 		{
-			RespExpData: `{"user_id":0,"balance":400}`,
+			RespExpData: `{"user_id":0,"balance":400,"currency":"RUB"}`,
 			ReqData:     []byte(`{"change":400,"comment":"My First","source":"Sberbank"}`),
 			Url:         "http://testserver:9001/users/0/balance",
 			Method:      "PATCH",
 		},
 		{
-			RespExpData: `{"user_id":0,"balance":200}`,
+			RespExpData: `{"user_id":0,"balance":200,"currency":"RUB"}`,
 			ReqData:     []byte(`{"change":-200,"comment":"My First","source":"Sberbank"}`),
 			Url:         "http://testserver:9001/users/0/balance",
 			Method:      "PATCH",
 		},
 		{
-			RespExpData: `{"source":{"user_id":0,"balance":0},"target":{"user_id":1,"balance":200}}`,
+			RespExpData: `{"transfer_id":1,"rate":1,"source":{"user_id":0,"balance":0,"currency":"RUB"},"target":{"user_id":1,"balance":200,"currency":"RUB"}}`,
 			ReqData:     []byte(`{"change":200,"comment":"My First","target_id":1}`),
 			Url:         "http://testserver:9001/users/0/balance/transfer",
 			Method:      "PATCH",
 		},
 		{
-			RespExpData: `{"user_id":0,"balance":0,"currency":"RUB"}`,
+			RespExpData: `{"user_id":0,"balance":0,"available":0,"held":0,"currency":"RUB"}`,
 			ReqData:     []byte(``),
 			Url:         "http://testserver:9001/users/0/balance",
 			Method:      "GET",
 		},
 		{
-			RespExpData: `{"user_id":1,"transactions":[{"init_balance":0,"change":200,"change_time":"17 Nov 09 20:34 UTC","source":"0","comment":"My First"}]}`,
+			RespExpData: `{"user_id":1,"transactions":[{"trans_id":4,"init_balance":0,"change":200,"currency":"RUB","change_time":"2009-11-17T20:34:58.651387Z","source":"0","comment":"My First","transfer_id":1,"rate":1,"counter_change":-200,"counter_currency":"RUB","client_id":"key:integration"}]}`,
 			ReqData:     []byte(`{"page":1,"per_page":1,"change_sort":false,"time_sort":false}`),
 			Url:         "http://testserver:9001/users/1/transactions",
 			Method:      "POST",
 		},
+		{
+			RespExpData: `{"user_id":1,"balance":300,"currency":"RUB"}`,
+			Header:      map[string]string{"Idempotency-Key":"retry-1"},
+			ReqData:     []byte(`{"change":100,"comment":"Retry","source":"Sberbank"}`),
+			Url:         "http://testserver:9001/users/1/balance",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"user_id":1,"balance":300,"currency":"RUB"}`,
+			Header:      map[string]string{"Idempotency-Key":"retry-1"},
+			ReqData:     []byte(`{"change":100,"comment":"Retry","source":"Sberbank"}`),
+			Url:         "http://testserver:9001/users/1/balance",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"code":"idempotency_conflict","message":"idempotency key was already used with a different request"}`,
+			Header:      map[string]string{"Idempotency-Key":"retry-1"},
+			ReqData:     []byte(`{"change":50,"comment":"Retry","source":"Sberbank"}`),
+			Url:         "http://testserver:9001/users/1/balance",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"hold_id":1,"user_id":1,"amount":100,"currency":"RUB","status":"held","balance":300,"held":100}`,
+			ReqData:     []byte(`{"amount":100,"comment":"Order 1"}`),
+			Url:         "http://testserver:9001/users/1/balance/reserve",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"user_id":1,"balance":300,"available":200,"held":100,"currency":"RUB"}`,
+			ReqData:     []byte(``),
+			Url:         "http://testserver:9001/users/1/balance",
+			Method:      "GET",
+		},
+		{
+			RespExpData: `{"code":"insufficient_funds","message":"insufficient funds"}`,
+			ReqData:     []byte(`{"change":-250,"comment":"Too much","source":"Sberbank"}`),
+			Url:         "http://testserver:9001/users/1/balance",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"hold_id":1,"user_id":1,"amount":100,"currency":"RUB","status":"captured","balance":200,"held":0}`,
+			ReqData:     []byte(``),
+			Url:         "http://testserver:9001/users/1/balance/holds/1/capture",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"code":"hold_settled","message":"hold is already captured"}`,
+			ReqData:     []byte(``),
+			Url:         "http://testserver:9001/users/1/balance/holds/1/release",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"trans_id":4,"amount":50,"remaining":150,"balances":[{"user_id":0,"balance":50,"currency":"RUB"},{"user_id":1,"balance":150,"currency":"RUB"}]}`,
+			ReqData:     []byte(`{"amount":50,"comment":"Partial refund"}`),
+			Url:         "http://testserver:9001/users/1/transactions/4/refund",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"code":"refund_exceeded","message":"refund exceeds remaining amount"}`,
+			ReqData:     []byte(`{"amount":200,"comment":"Too much"}`),
+			Url:         "http://testserver:9001/users/1/transactions/4/refund",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"trans_id":3,"amount":150,"remaining":0,"balances":[{"user_id":0,"balance":200,"currency":"RUB"},{"user_id":1,"balance":0,"currency":"RUB"}]}`,
+			ReqData:     []byte(``),
+			Url:         "http://testserver:9001/users/0/transactions/3/refund",
+			Method:      "PATCH",
+		},
+		{
+			RespExpData: `{"transfer_id":1,"source_id":0,"target_id":1,"amount":200,"currency":"RUB","target_amount":200,"target_currency":"RUB","rate":1,"comment":"My First","time":"2009-11-17T20:34:58.651387Z",` +
+				`"legs":[{"trans_id":3,"init_balance":200,"change":-200,"currency":"RUB","change_time":"2009-11-17T20:34:58.651387Z","source":"0","comment":"My First","transfer_id":1,"rate":1,"counter_change":200,"counter_currency":"RUB","client_id":"key:integration"},` +
+				`{"trans_id":4,"init_balance":0,"change":200,"currency":"RUB","change_time":"2009-11-17T20:34:58.651387Z","source":"0","comment":"My First","transfer_id":1,"rate":1,"counter_change":-200,"counter_currency":"RUB","client_id":"key:integration"}],` +
+				`"refunds":[{"trans_id":7,"init_balance":0,"change":50,"currency":"RUB","change_time":"2009-11-17T20:34:58.651387Z","source":"refund","comment":"Partial refund","refund_of":3,"client_id":"key:integration"},` +
+				`{"trans_id":8,"init_balance":200,"change":-50,"currency":"RUB","change_time":"2009-11-17T20:34:58.651387Z","source":"refund","comment":"Partial refund","refund_of":4,"client_id":"key:integration"},` +
+				`{"trans_id":9,"init_balance":50,"change":150,"currency":"RUB","change_time":"2009-11-17T20:34:58.651387Z","source":"refund","comment":"","refund_of":3,"client_id":"key:integration"},` +
+				`{"trans_id":10,"init_balance":150,"change":-150,"currency":"RUB","change_time":"2009-11-17T20:34:58.651387Z","source":"refund","comment":"","refund_of":4,"client_id":"key:integration"}]}`,
+			ReqData:     []byte(``),
+			Url:         "http://testserver:9001/transfers/1",
+			Method:      "GET",
+		},
+		{
+			RespExpData: `{"balanced":true,"imbalances":[]}`,
+			ReqData:     []byte(``),
+			Url:         "http://testserver:9001/ledger/check",
+			Method:      "GET",
+		},
+		{
+			RespExpData: `{"status":"ok"}`,
+			ReqData:     []byte(``),
+			Url:         "http://testserver:9001/healthz",
+			Method:      "GET",
+		},
 	}
 
 	for num, c := range cases {
@@ -96,6 +207,11 @@ func Test_All(t *testing.T){
 			t.Error(err)
 		}
 		req.Header.Set("Content-Type", "application/json")
+		// the key of the client "integration" with all scopes, see docker-compose-test.yml
+		req.Header.Set(httpServer.APIKeyHeader, "integration-key")
+		for k, v := range c.Header{
+			req.Header.Set(k, v)
+		}
 		resp, err := client.Do(req)
 		if err != nil {
 			t.Error(err)
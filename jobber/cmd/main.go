@@ -2,54 +2,304 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/fedorkolmykow/avitojob/pkg/auth"
+	"github.com/fedorkolmykow/avitojob/pkg/config"
 	"github.com/fedorkolmykow/avitojob/pkg/httpServer"
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
 	"github.com/fedorkolmykow/avitojob/pkg/postgres"
+	"github.com/fedorkolmykow/avitojob/pkg/ratelimit"
 	"github.com/fedorkolmykow/avitojob/pkg/redis"
 	"github.com/fedorkolmykow/avitojob/pkg/service"
+	"github.com/fedorkolmykow/avitojob/pkg/tracing"
 
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 
+// newRateProvider picks the source of exchange rates by cfg.Provider: "http" asks cfg.URL,
+// "file" reads cfg.File and "fixed" serves cfg.Fixed.
+func newRateProvider(cfg config.Rates) (service.RateProvider, error){
+	switch cfg.Provider{
+	case "http":
+		return service.NewHTTPRateProvider(cfg.URL, cfg.Timeout.Std()), nil
+	case "file":
+		return service.NewFileRateProvider(cfg.File)
+	case "fixed":
+		return service.NewFixedRateProvider(cfg.Fixed), nil
+	}
+	return nil, errors.New("unknown rate provider " + cfg.Provider)
+}
+
+// newRateRefresher saves the rate table every cfg.Refresh, zero turns it off.
+func newRateRefresher(cash redis.DbClient, rates service.RateProvider, cfg config.Rates) (*service.RateRefresher, error){
+	if cfg.Refresh == 0{
+		return nil, nil
+	}
+	return service.NewRateRefresher(cash, rates, cfg.Refresh.Std())
+}
+
+func newTimeouts(cfg config.HTTP) httpServer.Timeouts{
+	timeouts := httpServer.Timeouts{Default: cfg.RequestTimeout.Std(), Endpoints: map[string]time.Duration{}}
+	for route, timeout := range cfg.RequestTimeouts{
+		timeouts.Endpoints[route] = timeout.Std()
+	}
+	return timeouts
+}
+
+// newReconciler checks balances every cfg.Period, zero turns it off.
+func newReconciler(db postgres.DbClient, cfg config.Reconcile) (*service.Reconciler, error){
+	if cfg.Period == 0{
+		return nil, nil
+	}
+	return service.NewReconciler(db, cfg.Period.Std(), cfg.Repair, cfg.Dir)
+}
+
+// newAuthenticator tells clients by the keys of cfg, nil lets everybody in when authentication is disabled.
+func newAuthenticator(cfg config.Auth) (*auth.Authenticator, error){
+	if cfg.Disabled{
+		log.Warn("authentication is disabled, every request is served with all scopes")
+		return nil, nil
+	}
+	return auth.New(cfg)
+}
+
+// setupLog writes the log to cfg.File, which is rotated by size and age. The returned switch
+// raises the level from the configured one for a while.
+func setupLog(cfg config.Log) *logging.LevelSwitch{
+	log.SetFormatter(logging.NewRedactingFormatter(&log.JSONFormatter{}))
+	log.SetOutput(&lumberjack.Logger{
+		Filename: cfg.File,
+		MaxSize: cfg.MaxSize,
+		MaxAge: cfg.MaxAgeDays,
+		MaxBackups: cfg.MaxBackups,
+		Compress: cfg.Compress,
+		LocalTime: true,
+	})
+	level, _ := log.ParseLevel(cfg.Level)
+	return logging.NewLevelSwitch(level)
+}
+
+// loadConfig loads the configuration with the flags of a command, the process exits when it's invalid.
+func loadConfig(flags *flag.FlagSet, args []string) (*config.Config, *logging.LevelSwitch){
+	cfg, err := config.Load(flags, args)
+	if err != nil{
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return cfg, setupLog(cfg.Log)
+}
+
+// runReconcile is the reconcile command. It prints the report of one run as JSON
+// and returns 1 when discrepancies are found, 2 when the run fails.
+func runReconcile(args []string) int{
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "record corrective transactions for the changes missing from the history")
+	out := flags.String("out", "", "write the report to the file instead of stdout")
+	cfg, _ := loadConfig(flags, args)
+
+	dbCon := postgres.NewDbClient(cfg.Postgres)
+	defer func(){
+		e := dbCon.Shutdown()
+		if e != nil{
+			log.Warn(e)
+		}
+	}()
+	report, err := dbCon.Reconcile(context.Background(), *repair)
+	if err != nil{
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	body, err := report.MarshalJSON()
+	if err != nil{
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *out == ""{
+		_, err = os.Stdout.Write(append(body, '\n'))
+	} else{
+		err = ioutil.WriteFile(*out, body, 0666)
+	}
+	if err != nil{
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(report.Discrepancies) > 0{
+		return 1
+	}
+	return 0
+}
+
+// migrateUp applies the pending migrations of the schema.
+func migrateUp(cfg config.Postgres) error{
+	migrator, err := postgres.NewMigrator(cfg)
+	if err != nil{
+		return err
+	}
+	defer func(){
+		e := migrator.Close()
+		if e != nil{
+			log.Warn(e)
+		}
+	}()
+	_, err = migrator.Up(context.Background())
+	return err
+}
+
+// runMigrate is the migrate command: "up" applies the pending migrations, "down [n]" reverts the last n (1 by default),
+// "status" lists the migrations and "force <version>" marks the migrations up to version as applied without running them.
+func runMigrate(args []string) int{
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	cfg, _ := loadConfig(flags, args)
+	args = flags.Args()
+	if len(args) == 0{
+		fmt.Fprintln(os.Stderr, "usage: migrate [flags] up | down [n] | status | force <version>")
+		return 2
+	}
+	migrator, err := postgres.NewMigrator(cfg.Postgres)
+	if err != nil{
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer func(){
+		e := migrator.Close()
+		if e != nil{
+			log.Warn(e)
+		}
+	}()
+	ctx := context.Background()
+	var done []postgres.Migration
+	switch{
+	case args[0] == "up" && len(args) == 1:
+		done, err = migrator.Up(ctx)
+	case args[0] == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2{
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0{
+				fmt.Fprintln(os.Stderr, "invalid number of steps " + args[1])
+				return 2
+			}
+		}
+		done, err = migrator.Down(ctx, steps)
+	case args[0] == "status" && len(args) == 1:
+		var status []postgres.MigrationStatus
+		status, err = migrator.Status(ctx)
+		for _, s := range status{
+			applied := "pending"
+			if s.Applied(){
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s %s\n", s.Version, s.Name, applied)
+		}
+	case args[0] == "force" && len(args) == 2:
+		var version int
+		version, err = strconv.Atoi(args[1])
+		if err != nil{
+			fmt.Fprintln(os.Stderr, "invalid version " + args[1])
+			return 2
+		}
+		err = migrator.Force(ctx, version)
+	default:
+		fmt.Fprintln(os.Stderr, "usage: migrate [flags] up | down [n] | status | force <version>")
+		return 2
+	}
+	for _, mg := range done{
+		fmt.Printf("%s %04d_%s\n", args[0], mg.Version, mg.Name)
+	}
+	if err != nil{
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
 
 func main() {
-	log.SetFormatter(&log.JSONFormatter{})
-	switch os.Getenv("LOG_LEVEL"){
-		case "TRACE": log.SetLevel(log.TraceLevel)
-		case "WARN": log.SetLevel(log.WarnLevel)
-		case "FATAL": log.SetLevel(log.FatalLevel)
-		default: log.SetLevel(log.FatalLevel)
-	}
-	err := os.Mkdir("logs", 0777)
-	if err != nil {
-		log.Warn(err)
-	}
-	file, err := os.OpenFile("logs/jobber.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err == nil {
-		log.SetOutput(file)
-	} else {
-	    log.Warn("Failed to log to file, using default stderr")
-	}
-
-	redCon := redis.NewDb()
-    dbCon := postgres.NewDbClient()
-    swc := service.NewService(dbCon, redCon)
-	router := httpServer.NewHTTPServer(swc)
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-"){
+		switch os.Args[1]{
+		case "reconcile":
+			os.Exit(runReconcile(os.Args[2:]))
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		default:
+			fmt.Fprintln(os.Stderr, "unknown command " + os.Args[1])
+			os.Exit(2)
+		}
+	}
+	cfg, levels := loadConfig(flag.CommandLine, os.Args[1:])
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil{
+		log.Fatal(err)
+	}
+
+	if cfg.MigrateOnStart{
+		err = migrateUp(cfg.Postgres)
+		if err != nil{
+			log.Fatal(err)
+		}
+	}
+	redCon := redis.NewDb(cfg.Redis)
+    dbCon := postgres.NewDbClient(cfg.Postgres)
+	if collector, ok := dbCon.(prometheus.Collector); ok{
+		prometheus.MustRegister(collector)
+	}
+	rates, err := newRateProvider(cfg.Rates)
+	if err != nil{
+		log.Fatal(err)
+	}
+    swc := service.NewService(dbCon, redCon, rates)
+	// requests are served in baseCtx, so they are cancelled when the shutdown deadline passes
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	refresher, err := newRateRefresher(redCon, rates, cfg.Rates)
+	if err != nil{
+		log.Fatal(err)
+	}
+	if refresher != nil{
+		go refresher.Run(baseCtx)
+	}
+	reconciler, err := newReconciler(dbCon, cfg.Reconcile)
+	if err != nil{
+		log.Fatal(err)
+	}
+	if reconciler != nil{
+		go reconciler.Run(baseCtx)
+	}
+	health := httpServer.NewHealth(cfg.HTTP.HealthTimeout.Std(), map[string]httpServer.Pinger{
+		"postgres": dbCon,
+		"redis": redCon,
+	})
+	authn, err := newAuthenticator(cfg.Auth)
+	if err != nil{
+		log.Fatal(err)
+	}
+	admin := httpServer.NewAdmin(levels, cfg.Log.RaiseFor.Std(), cfg.Log.MaxRaise.Std())
+	limiter := ratelimit.New(redCon, cfg.RateLimit)
+	router := httpServer.NewHTTPServer(swc, newTimeouts(cfg.HTTP), health, admin, authn, limiter)
 	srv := &http.Server{
-		Addr:    os.Getenv("HTTP_PORT"),
+		Addr:    cfg.HTTP.Port,
 		Handler: router,
+		BaseContext: func(net.Listener) context.Context{
+			return baseCtx
+		},
 	}
 
 	go func() {
 
-		log.Trace("starting HTTP server at", os.Getenv("HTTP_PORT"))
+		log.Trace("starting HTTP server at", cfg.HTTP.Port)
 		err = srv.ListenAndServe()
 		if err != nil && err != http.ErrServerClosed{
 			log.Fatal(err)
@@ -60,9 +310,17 @@ func main() {
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	<-done
 
-	wait, err := strconv.Atoi(os.Getenv("TIME_TO_SHUTDOWN"))
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(wait)*time.Second)
+	// readiness fails from now on, load balancers get ShutdownDelay to stop sending requests
+	health.Shutdown()
+	time.Sleep(cfg.ShutdownDelay.Std())
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Std())
+	// requests still running when the shutdown deadline passes are cancelled
+	go func(){
+		<-ctx.Done()
+		cancelBase()
+	}()
 	defer func(){
+		cancelBase()
 		e := redCon.Shutdown()
 		if e != nil{
 			log.Warn(e)
@@ -71,11 +329,17 @@ func main() {
 		if e != nil{
 			log.Warn(e)
 		}
+		e = shutdownTracing(ctx)
+		if e != nil{
+			log.Warn(e)
+		}
 		cancel()
 	}()
 	err = srv.Shutdown(ctx)
 	if err != nil{
-		log.Fatalf("Graceful Server Shutdown Failed:%+v", err)
+		// the deferred shutdown of Redis, Postgres and tracing still runs
+		log.Errorf("Graceful Server Shutdown Failed:%+v", err)
+		return
 	}
 	log.Trace("Server Was Gracefully Stopped")
-}
\ No newline at end of file
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/fedorkolmykow/avitojob/pkg/config"
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+)
+
+// Scopes of clients. A client may only call the endpoints of its scopes.
+const(
+	ScopeBalanceRead = "balance:read"
+	ScopeBalanceCredit = "balance:credit"
+	ScopeBalanceDebit = "balance:debit"
+	ScopeTransfer = "transfer"
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists all scopes.
+var Scopes = []string{ScopeBalanceRead, ScopeBalanceCredit, ScopeBalanceDebit, ScopeTransfer, ScopeAdmin}
+
+// Anonymous is the client of every request when authentication is disabled.
+var Anonymous = &Client{ID: "anonymous", Scopes: Scopes}
+
+// Client is an authenticated caller of the API. ID is prefixed with the source of the identity,
+// "key:<client>" for API keys and "jwt:<iss>/<sub>" for tokens, so a token can't pose as a client
+// of an API key. It attributes transactions and scopes idempotency keys.
+type Client struct{
+	ID string
+	Scopes []string
+}
+
+func keyClientID(client string) string{
+	return "key:" + client
+}
+
+func tokenClientID(issuer string, subject string) string{
+	return "jwt:" + issuer + "/" + subject
+}
+
+// Has tells whether the client has scope.
+func (c *Client) Has(scope string) bool{
+	return contains(c.Scopes, scope)
+}
+
+type ctxKey struct{}
+
+// NewContext returns ctx carrying the client of a request.
+func NewContext(ctx context.Context, client *Client) context.Context{
+	return context.WithValue(ctx, ctxKey{}, client)
+}
+
+// FromContext returns the client of the request of ctx.
+func FromContext(ctx context.Context) (client *Client, ok bool){
+	client, ok = ctx.Value(ctxKey{}).(*Client)
+	return
+}
+
+// Authenticator tells clients by their static API keys or by the JWTs they present.
+type Authenticator struct{
+	// keys holds the clients by the hex SHA-256 of their keys, so keys themselves aren't kept
+	keys map[string]*Client
+	secret []byte
+	publicKey *rsa.PublicKey
+	issuer string
+	audience string
+	leeway time.Duration
+	now func() time.Time
+}
+
+func unauthorized(message string) error{
+	return &m.Error{Kind: m.KindUnauthorized, Code: m.CodeUnauthorized, Message: message}
+}
+
+// APIKey returns the client of key.
+func (a *Authenticator) APIKey(key string) (*Client, error){
+	sum := sha256.Sum256([]byte(key))
+	client, ok := a.keys[hex.EncodeToString(sum[:])]
+	if !ok{
+		return nil, unauthorized("unknown API key")
+	}
+	return client, nil
+}
+
+func checkScopes(scopes []string) error{
+	for _, scope := range scopes{
+		if !contains(Scopes, scope){
+			return errors.New("unknown scope " + scope)
+		}
+	}
+	return nil
+}
+
+func readPublicKey(path string) (*rsa.PublicKey, error){
+	body, err := ioutil.ReadFile(path)
+	if err != nil{
+		return nil, err
+	}
+	block, _ := pem.Decode(body)
+	if block == nil{
+		return nil, errors.New(path + " isn't PEM")
+	}
+	var key interface{}
+	switch block.Type{
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, errors.New(path + " doesn't hold a public key")
+	}
+	if err != nil{
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok{
+		return nil, errors.New(path + " doesn't hold an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// New makes the authenticator of cfg, reading the RSA public key of RS256 tokens.
+func New(cfg config.Auth) (*Authenticator, error){
+	a := &Authenticator{
+		keys: map[string]*Client{},
+		issuer: cfg.Issuer,
+		audience: cfg.Audience,
+		leeway: cfg.Leeway.Std(),
+		now: time.Now,
+	}
+	for _, key := range cfg.APIKeys{
+		err := checkScopes(key.Scopes)
+		if err != nil{
+			return nil, errors.New("API key of " + key.Client + ": " + err.Error())
+		}
+		a.keys[strings.ToLower(key.SHA256)] = &Client{ID: keyClientID(key.Client), Scopes: key.Scopes}
+	}
+	if cfg.Secret != ""{
+		a.secret = []byte(cfg.Secret)
+	}
+	if cfg.PublicKeyFile != ""{
+		var err error
+		a.publicKey, err = readPublicKey(cfg.PublicKeyFile)
+		if err != nil{
+			return nil, err
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fedorkolmykow/avitojob/pkg/config"
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+)
+
+const secret = "jwt-secret"
+
+func encode(s string) string{
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func hs256(header, claims string) string{
+	signed := encode(header) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey, claims string) string{
+	signed := encode(`{"alg":"RS256","typ":"JWT"}`) + "." + encode(claims)
+	sum := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil{
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newAuthenticator(t *testing.T) (*Authenticator, *rsa.PrivateKey){
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil{
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil{
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0666)
+	if err != nil{
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("billing-key"))
+	a, err := New(config.Auth{
+		APIKeys: []config.APIKey{{Client: "billing", SHA256: hex.EncodeToString(sum[:]), Scopes: []string{ScopeTransfer}}},
+		Secret: secret,
+		PublicKeyFile: path,
+		Issuer: "issuer",
+		Audience: "jobber",
+		Leeway: config.Duration(time.Minute),
+	})
+	if err != nil{
+		t.Fatal(err)
+	}
+	a.now = func() time.Time{
+		return time.Unix(1000000, 0)
+	}
+	return a, key
+}
+
+func TestAPIKey(t *testing.T){
+	a, _ := newAuthenticator(t)
+	client, err := a.APIKey("billing-key")
+	if err != nil || !reflect.DeepEqual(client, &Client{ID: "key:billing", Scopes: []string{ScopeTransfer}}){
+		t.Errorf("unexpected client: %+v, %v", client, err)
+	}
+	_, err = a.APIKey("billing")
+	if !errors.Is(err, m.ErrUnauthorized){
+		t.Errorf("unknown key is accepted: %v", err)
+	}
+	_, err = New(config.Auth{APIKeys: []config.APIKey{{Client: "billing", Scopes: []string{"everything"}}}})
+	if err == nil{
+		t.Errorf("unknown scope is accepted")
+	}
+}
+
+func TestToken(t *testing.T){
+	a, key := newAuthenticator(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil{
+		t.Fatal(err)
+	}
+	const hs = `{"alg":"HS256","typ":"JWT"}`
+	cases := []struct{
+		Token  string
+		Client *Client
+		Err    string
+	}{
+		{
+			Token: hs256(hs, `{"sub":"shop","iss":"issuer","aud":"jobber","exp":1000100,"scope":"balance:read transfer"}`),
+			Client: &Client{ID: "jwt:issuer/shop", Scopes: []string{ScopeBalanceRead, ScopeTransfer}},
+		},
+		{
+			Token: rs256(t, key, `{"sub":"bank","iss":"issuer","aud":["other","jobber"],"exp":1000100,"nbf":1000030,"scp":["admin"]}`),
+			Client: &Client{ID: "jwt:issuer/bank", Scopes: []string{ScopeAdmin}},
+		},
+		{
+			// expired within the leeway
+			Token: hs256(hs, `{"sub":"shop","iss":"issuer","aud":"jobber","exp":999950}`),
+			Client: &Client{ID: "jwt:issuer/shop", Scopes: []string{}},
+		},
+		{Token: hs256(hs, `{"sub":"shop","iss":"issuer","aud":"jobber","exp":999900}`), Err: "token expired"},
+		{Token: hs256(hs, `{"sub":"shop","iss":"issuer","aud":"jobber"}`), Err: "token expired"},
+		{Token: hs256(hs, `{"sub":"shop","iss":"issuer","aud":"jobber","exp":1000100,"nbf":1000100}`), Err: "token isn't valid yet"},
+		{Token: hs256(hs, `{"sub":"shop","iss":"other","aud":"jobber","exp":1000100}`), Err: "token of another issuer"},
+		{Token: hs256(hs, `{"sub":"shop","iss":"issuer","aud":"other","exp":1000100}`), Err: "token for another audience"},
+		{Token: hs256(hs, `{"iss":"issuer","aud":"jobber","exp":1000100}`), Err: "token without subject"},
+		{Token: rs256(t, other, `{"sub":"bank","iss":"issuer","aud":"jobber","exp":1000100}`), Err: "invalid token signature"},
+		{Token: hs256(hs, `{"sub":"shop","exp":1000100}`)[:40] + "x", Err: "malformed token"},
+		{
+			Token: encode(`{"alg":"none"}`) + "." + encode(`{"sub":"shop","iss":"issuer","aud":"jobber","exp":1000100}`) + ".",
+			Err: "unsupported token algorithm none",
+		},
+	}
+	for num, c := range cases{
+		client, err := a.Token(c.Token)
+		if c.Err != ""{
+			var e *m.Error
+			if !errors.As(err, &e) || e.Kind != m.KindUnauthorized || e.Message != c.Err{
+				t.Errorf("[%d] unexpected error: %v, expected: %s", num, err, c.Err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(client, c.Client){
+			t.Errorf("[%d] unexpected client: %+v, %v, expected: %+v", num, client, err, c.Client)
+		}
+	}
+}
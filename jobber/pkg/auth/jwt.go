@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+type header struct{
+	Alg string `json:"alg"`
+}
+
+// audience is a single string or a list of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error{
+	var one string
+	if json.Unmarshal(data, &one) == nil{
+		*a = audience{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+type claims struct{
+	Subject string `json:"sub"`
+	Issuer string `json:"iss"`
+	Audience audience `json:"aud"`
+	Expires *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	// Scope is a space separated list of scopes, Scp is an array of them
+	Scope string `json:"scope"`
+	Scp []string `json:"scp"`
+}
+
+func (c *claims) scopes() []string{
+	scopes := append([]string{}, c.Scp...)
+	return append(scopes, strings.Fields(c.Scope)...)
+}
+
+// verify checks the signature of the token with the key of its algorithm. Tokens
+// signed by algorithms without a configured key, "none" among them, are rejected.
+func (a *Authenticator) verify(alg string, signed string, signature []byte) error{
+	switch alg{
+	case "HS256":
+		if a.secret == nil{
+			return unauthorized("HS256 tokens aren't accepted")
+		}
+		mac := hmac.New(sha256.New, a.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature){
+			return unauthorized("invalid token signature")
+		}
+	case "RS256":
+		if a.publicKey == nil{
+			return unauthorized("RS256 tokens aren't accepted")
+		}
+		sum := sha256.Sum256([]byte(signed))
+		if rsa.VerifyPKCS1v15(a.publicKey, crypto.SHA256, sum[:], signature) != nil{
+			return unauthorized("invalid token signature")
+		}
+	default:
+		return unauthorized("unsupported token algorithm " + alg)
+	}
+	return nil
+}
+
+// Token returns the client of a JWT signed by HS256 or RS256. The client is the subject of the token
+// within its issuer, its scopes come from the scope or scp claims. Tokens must expire, exp and nbf
+// are checked with leeway.
+func (a *Authenticator) Token(token string) (*Client, error){
+	parts := strings.Split(token, ".")
+	if len(parts) != 3{
+		return nil, unauthorized("malformed token")
+	}
+	var h header
+	var c claims
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(headerJSON, &h) != nil{
+		return nil, unauthorized("malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil{
+		return nil, unauthorized("malformed token signature")
+	}
+	err = a.verify(h.Alg, parts[0] + "." + parts[1], signature)
+	if err != nil{
+		return nil, err
+	}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(claimsJSON, &c) != nil{
+		return nil, unauthorized("malformed token claims")
+	}
+	now := a.now()
+	if c.Expires == nil || now.After(unixTime(*c.Expires).Add(a.leeway)){
+		return nil, unauthorized("token expired")
+	}
+	if c.NotBefore != nil && now.Add(a.leeway).Before(unixTime(*c.NotBefore)){
+		return nil, unauthorized("token isn't valid yet")
+	}
+	if a.issuer != "" && c.Issuer != a.issuer{
+		return nil, unauthorized("token of another issuer")
+	}
+	if a.audience != "" && !contains(c.Audience, a.audience){
+		return nil, unauthorized("token for another audience")
+	}
+	if c.Subject == ""{
+		return nil, unauthorized("token without subject")
+	}
+	return &Client{ID: tokenClientID(c.Issuer, c.Subject), Scopes: c.scopes()}, nil
+}
+
+func unixTime(seconds float64) time.Time{
+	return time.Unix(0, int64(seconds * float64(time.Second)))
+}
+
+func contains(list []string, s string) bool{
+	for _, v := range list{
+		if v == s{
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,596 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the service. Values come from the defaults, then the file
+// named by -config or CONFIG_FILE (YAML or TOML by extension), then the environment and then flags.
+type Config struct{
+	HTTP HTTP `yaml:"http" toml:"http"`
+	Postgres Postgres `yaml:"postgres" toml:"postgres"`
+	Redis Redis `yaml:"redis" toml:"redis"`
+	Rates Rates `yaml:"rates" toml:"rates"`
+	Reconcile Reconcile `yaml:"reconcile" toml:"reconcile"`
+	Log Log `yaml:"log" toml:"log"`
+	Auth Auth `yaml:"auth" toml:"auth"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Tracing Tracing `yaml:"tracing" toml:"tracing"`
+	// MigrateOnStart applies the pending migrations before the server starts.
+	MigrateOnStart bool `yaml:"migrate_on_start" toml:"migrate_on_start"`
+	// ShutdownDelay is the time between failing readiness and closing the listener on a stop signal,
+	// load balancers stop sending requests meanwhile.
+	ShutdownDelay Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	// ShutdownTimeout is the time requests are given to finish after a stop signal.
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+type HTTP struct{
+	Port string `yaml:"port" toml:"port"`
+	// RequestTimeout is the deadline of requests, zero means none.
+	RequestTimeout Duration `yaml:"request_timeout" toml:"request_timeout"`
+	// RequestTimeouts are the deadlines of single endpoints by route name.
+	RequestTimeouts map[string]Duration `yaml:"request_timeouts" toml:"request_timeouts"`
+	// HealthTimeout bounds the ping of every dependency by the readiness probe.
+	HealthTimeout Duration `yaml:"health_timeout" toml:"health_timeout"`
+}
+
+type Postgres struct{
+	URL string `yaml:"url" toml:"url"`
+	// MaxOpenConns and MaxIdleConns limit the pool, zero MaxOpenConns means no limit.
+	MaxOpenConns int `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns int `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	// ConnMaxLifetime closes connections older than it, zero keeps them forever.
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	// MaxRetries is the number of retries of transactions failed by serialization errors or deadlocks.
+	MaxRetries int `yaml:"max_retries" toml:"max_retries"`
+}
+
+type Redis struct{
+	URL string `yaml:"url" toml:"url"`
+	// MaxIdle and MaxActive limit the pool, zero MaxActive means no limit.
+	MaxIdle int `yaml:"max_idle" toml:"max_idle"`
+	MaxActive int `yaml:"max_active" toml:"max_active"`
+	IdleTimeout Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+}
+
+// Rates picks the source of exchange rates: "http" asks URL, "file" reads File and "fixed" serves Fixed.
+type Rates struct{
+	Provider string `yaml:"provider" toml:"provider"`
+	URL string `yaml:"url" toml:"url"`
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+	File string `yaml:"file" toml:"file"`
+	Fixed map[string]float64 `yaml:"fixed" toml:"fixed"`
+	// Refresh is the period of saving the rate table, zero turns it off.
+	Refresh Duration `yaml:"refresh" toml:"refresh"`
+}
+
+type Reconcile struct{
+	// Period is the period of reconciliation, zero turns it off.
+	Period Duration `yaml:"period" toml:"period"`
+	Repair bool `yaml:"repair" toml:"repair"`
+	Dir string `yaml:"dir" toml:"dir"`
+}
+
+type Log struct{
+	// Level is one of the logrus levels in any case: TRACE, DEBUG, INFO, WARN, ERROR, FATAL or PANIC.
+	Level string `yaml:"level" toml:"level"`
+	File string `yaml:"file" toml:"file"`
+	// MaxSize is the size in megabytes the file is rotated at.
+	MaxSize int `yaml:"max_size" toml:"max_size"`
+	// MaxAgeDays and MaxBackups bound the rotated files kept, zero keeps them all.
+	MaxAgeDays int `yaml:"max_age_days" toml:"max_age_days"`
+	MaxBackups int `yaml:"max_backups" toml:"max_backups"`
+	// Compress gzips rotated files.
+	Compress bool `yaml:"compress" toml:"compress"`
+	// RaiseFor is the time the admin endpoint raises the level for unless asked otherwise,
+	// MaxRaise is the longest it is allowed to.
+	RaiseFor Duration `yaml:"raise_for" toml:"raise_for"`
+	MaxRaise Duration `yaml:"max_raise" toml:"max_raise"`
+}
+
+// Auth tells clients by static API keys and by JWTs signed with Secret (HS256)
+// or RS256 tokens, which are verified with the RSA public key in PublicKeyFile.
+type Auth struct{
+	// Disabled lets every request in with all scopes.
+	Disabled bool `yaml:"disabled" toml:"disabled"`
+	APIKeys []APIKey `yaml:"api_keys" toml:"api_keys"`
+	Secret string `yaml:"secret" toml:"secret"`
+	PublicKeyFile string `yaml:"public_key_file" toml:"public_key_file"`
+	// Issuer and Audience, when set, must match the iss and aud claims of tokens.
+	Issuer string `yaml:"issuer" toml:"issuer"`
+	Audience string `yaml:"audience" toml:"audience"`
+	// Leeway is the clock skew allowed checking the exp and nbf claims.
+	Leeway Duration `yaml:"leeway" toml:"leeway"`
+}
+
+// APIKey is a static key of Client. Only the hex SHA-256 of the key is configured.
+type APIKey struct{
+	Client string `yaml:"client" toml:"client"`
+	SHA256 string `yaml:"sha256" toml:"sha256"`
+	Scopes []string `yaml:"scopes" toml:"scopes"`
+}
+
+// RateLimit limits the requests of every client and to every user by token buckets kept in Redis,
+// so all instances share them. Routes holds the limits of routes by name, others get Default.
+type RateLimit struct{
+	Default RouteLimit `yaml:"default" toml:"default"`
+	Routes map[string]RouteLimit `yaml:"routes" toml:"routes"`
+	// FailOpen lets requests in when Redis is unavailable, otherwise they are answered 503.
+	FailOpen bool `yaml:"fail_open" toml:"fail_open"`
+}
+
+// RouteLimit limits the requests of a client and the requests to a user, the user_id of the path.
+type RouteLimit struct{
+	Client Limit `yaml:"client" toml:"client"`
+	User Limit `yaml:"user" toml:"user"`
+}
+
+// Limit refills Rate tokens a second up to Burst, every request takes one. Zero Rate means no limit.
+type Limit struct{
+	Rate float64 `yaml:"rate" toml:"rate"`
+	Burst int `yaml:"burst" toml:"burst"`
+}
+
+// Tracing picks the exporter of spans: "none", "stdout" writes them to File or stdout as JSON
+// and "otlp" sends them to the OTLP/HTTP collector at Endpoint.
+type Tracing struct{
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	File string `yaml:"file" toml:"file"`
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	// Insecure sends spans to the collector over plain HTTP.
+	Insecure bool `yaml:"insecure" toml:"insecure"`
+	// SampleRatio is the share of traces started by the service which are recorded.
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// Duration is written like "1m30s", a plain number is a number of seconds.
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration{
+	return time.Duration(d)
+}
+
+func (d *Duration) UnmarshalText(text []byte) error{
+	s := string(text)
+	if seconds, err := strconv.Atoi(s); err == nil{
+		*d = Duration(time.Duration(seconds) * time.Second)
+		return nil
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil{
+		return errors.New("invalid duration " + s)
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error){
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() Config{
+	return Config{
+		HTTP: HTTP{Port: ":9000", RequestTimeouts: map[string]Duration{}, HealthTimeout: Duration(2 * time.Second)},
+		Postgres: Postgres{MaxIdleConns: 2, MaxRetries: 3},
+		Redis: Redis{MaxIdle: 5, IdleTimeout: Duration(240 * time.Second)},
+		Rates: Rates{Provider: "http", Timeout: Duration(5 * time.Second), Refresh: Duration(time.Hour)},
+		Reconcile: Reconcile{Period: Duration(24 * time.Hour), Dir: "reports"},
+		Log: Log{Level: "FATAL", File: "logs/jobber.log", MaxSize: 100, MaxAgeDays: 30, MaxBackups: 10, Compress: true,
+			RaiseFor: Duration(10 * time.Minute), MaxRaise: Duration(time.Hour)},
+		RateLimit: RateLimit{
+			Default: RouteLimit{Client: Limit{Rate: 50, Burst: 100}, User: Limit{Rate: 10, Burst: 20}},
+			Routes: map[string]RouteLimit{
+				"transfer": {Client: Limit{Rate: 10, Burst: 20}, User: Limit{Rate: 1, Burst: 5}},
+			},
+			FailOpen: true,
+		},
+		Tracing: Tracing{Exporter: "none", Endpoint: "localhost:4318", SampleRatio: 1},
+		MigrateOnStart: true,
+		ShutdownTimeout: Duration(10 * time.Second),
+	}
+}
+
+// setting is a value which can be set by an environment variable and by the flag named after it,
+// DB_MAX_RETRIES is set by -db-max-retries.
+type setting struct{
+	Env string
+	Usage string
+	Set func(string) error
+}
+
+func (s setting) flag() string{
+	return strings.ReplaceAll(strings.ToLower(s.Env), "_", "-")
+}
+
+func stringVar(p *string) func(string) error{
+	return func(v string) error{
+		*p = v
+		return nil
+	}
+}
+
+func intVar(p *int) func(string) error{
+	return func(v string) (err error){
+		*p, err = strconv.Atoi(v)
+		if err != nil{
+			return errors.New("not a number")
+		}
+		return
+	}
+}
+
+func boolVar(p *bool) func(string) error{
+	return func(v string) (err error){
+		*p, err = strconv.ParseBool(v)
+		if err != nil{
+			return errors.New("not a boolean")
+		}
+		return
+	}
+}
+
+func floatVar(p *float64) func(string) error{
+	return func(v string) (err error){
+		*p, err = strconv.ParseFloat(v, 64)
+		if err != nil{
+			return errors.New("not a number")
+		}
+		return
+	}
+}
+
+func durationVar(p *Duration) func(string) error{
+	return func(v string) error{
+		return p.UnmarshalText([]byte(v))
+	}
+}
+
+// ratesVar reads rates like "USD:0.0125,EUR:0.011".
+func ratesVar(p *map[string]float64) func(string) error{
+	return func(v string) error{
+		rates := map[string]float64{}
+		for _, pair := range strings.Split(v, ","){
+			parts := strings.Split(pair, ":")
+			if len(parts) != 2{
+				return errors.New("invalid entry " + pair)
+			}
+			rate, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil{
+				return errors.New("invalid entry " + pair)
+			}
+			rates[parts[0]] = rate
+		}
+		*p = rates
+		return nil
+	}
+}
+
+// apiKeysVar reads keys like "billing:<sha256>:balance:read|transfer,ops:<sha256>:admin".
+func apiKeysVar(p *[]APIKey) func(string) error{
+	return func(v string) error{
+		keys := []APIKey{}
+		for _, entry := range strings.Split(v, ","){
+			parts := strings.SplitN(entry, ":", 3)
+			if len(parts) != 3{
+				return errors.New("invalid entry " + entry)
+			}
+			keys = append(keys, APIKey{Client: parts[0], SHA256: parts[1], Scopes: strings.Split(parts[2], "|")})
+		}
+		*p = keys
+		return nil
+	}
+}
+
+// rateLimitsVar reads limits like "transfer:client=10:20,transfer:user=1:5,default:client=50:100",
+// route:bucket=rate:burst. Limits which aren't named keep their values.
+func rateLimitsVar(c *RateLimit) func(string) error{
+	return func(v string) error{
+		for _, entry := range strings.Split(v, ","){
+			parts := strings.Split(entry, "=")
+			if len(parts) != 2{
+				return errors.New("invalid entry " + entry)
+			}
+			name := strings.Split(parts[0], ":")
+			values := strings.Split(parts[1], ":")
+			if len(name) != 2 || len(values) != 2{
+				return errors.New("invalid entry " + entry)
+			}
+			rate, err := strconv.ParseFloat(values[0], 64)
+			if err != nil{
+				return errors.New("invalid entry " + entry)
+			}
+			burst, err := strconv.Atoi(values[1])
+			if err != nil{
+				return errors.New("invalid entry " + entry)
+			}
+			route := c.Default
+			if name[0] != "default"{
+				route = c.Routes[name[0]]
+			}
+			switch name[1]{
+			case "client":
+				route.Client = Limit{Rate: rate, Burst: burst}
+			case "user":
+				route.User = Limit{Rate: rate, Burst: burst}
+			default:
+				return errors.New("invalid entry " + entry)
+			}
+			if name[0] == "default"{
+				c.Default = route
+				continue
+			}
+			if c.Routes == nil{
+				c.Routes = map[string]RouteLimit{}
+			}
+			c.Routes[name[0]] = route
+		}
+		return nil
+	}
+}
+
+// timeoutsVar reads timeouts like "transfer=10s,getTransactions=30s".
+func timeoutsVar(p *map[string]Duration) func(string) error{
+	return func(v string) error{
+		timeouts := map[string]Duration{}
+		if v == ""{
+			*p = timeouts
+			return nil
+		}
+		for _, pair := range strings.Split(v, ","){
+			parts := strings.Split(pair, "=")
+			if len(parts) != 2{
+				return errors.New("invalid entry " + pair)
+			}
+			var timeout Duration
+			if timeout.UnmarshalText([]byte(parts[1])) != nil{
+				return errors.New("invalid entry " + pair)
+			}
+			timeouts[parts[0]] = timeout
+		}
+		*p = timeouts
+		return nil
+	}
+}
+
+func (c *Config) settings() []setting{
+	return []setting{
+		{"HTTP_PORT", "address of the HTTP server, like :9000", stringVar(&c.HTTP.Port)},
+		{"REQUEST_TIMEOUT", "deadline of requests, 0 means none", durationVar(&c.HTTP.RequestTimeout)},
+		{"REQUEST_TIMEOUTS", "deadlines of endpoints, like transfer=10s,getTransactions=30s", timeoutsVar(&c.HTTP.RequestTimeouts)},
+		{"HEALTH_TIMEOUT", "timeout of pinging a dependency by the readiness probe", durationVar(&c.HTTP.HealthTimeout)},
+		{"DATABASE_URL", "Postgres connection URL", stringVar(&c.Postgres.URL)},
+		{"DB_MAX_OPEN_CONNS", "maximum of open Postgres connections, 0 means no limit", intVar(&c.Postgres.MaxOpenConns)},
+		{"DB_MAX_IDLE_CONNS", "maximum of idle Postgres connections", intVar(&c.Postgres.MaxIdleConns)},
+		{"DB_CONN_MAX_LIFETIME", "lifetime of Postgres connections, 0 means forever", durationVar(&c.Postgres.ConnMaxLifetime)},
+		{"DB_MAX_RETRIES", "retries of transactions failed by serialization errors or deadlocks", intVar(&c.Postgres.MaxRetries)},
+		{"REDIS_URL", "Redis address, like redis:6379", stringVar(&c.Redis.URL)},
+		{"REDIS_MAX_IDLE", "maximum of idle Redis connections", intVar(&c.Redis.MaxIdle)},
+		{"REDIS_MAX_ACTIVE", "maximum of Redis connections, 0 means no limit", intVar(&c.Redis.MaxActive)},
+		{"REDIS_IDLE_TIMEOUT", "time after which idle Redis connections are closed", durationVar(&c.Redis.IdleTimeout)},
+		{"RATE_PROVIDER", "source of exchange rates: http, file or fixed", stringVar(&c.Rates.Provider)},
+		{"CURRENCY_URL", "URL of the exchange rates API", stringVar(&c.Rates.URL)},
+		{"RATE_TIMEOUT", "timeout of the exchange rates API", durationVar(&c.Rates.Timeout)},
+		{"RATE_FILE", "JSON file of exchange rates", stringVar(&c.Rates.File)},
+		{"RATES", "fixed exchange rates, like USD:0.0125,EUR:0.011", ratesVar(&c.Rates.Fixed)},
+		{"RATE_REFRESH", "period of saving the rate table, 0 turns it off", durationVar(&c.Rates.Refresh)},
+		{"RECONCILE_PERIOD", "period of balance reconciliation, 0 turns it off", durationVar(&c.Reconcile.Period)},
+		{"RECONCILE_REPAIR", "record corrections for discrepancies found by reconciliation", boolVar(&c.Reconcile.Repair)},
+		{"RECONCILE_DIR", "directory of reconciliation reports", stringVar(&c.Reconcile.Dir)},
+		{"LOG_LEVEL", "log level: TRACE, DEBUG, INFO, WARN, ERROR, FATAL or PANIC", stringVar(&c.Log.Level)},
+		{"LOG_FILE", "log file", stringVar(&c.Log.File)},
+		{"LOG_MAX_SIZE", "size in megabytes the log file is rotated at", intVar(&c.Log.MaxSize)},
+		{"LOG_MAX_AGE_DAYS", "days rotated log files are kept, 0 means forever", intVar(&c.Log.MaxAgeDays)},
+		{"LOG_MAX_BACKUPS", "number of rotated log files kept, 0 means all", intVar(&c.Log.MaxBackups)},
+		{"LOG_COMPRESS", "gzip rotated log files", boolVar(&c.Log.Compress)},
+		{"LOG_RAISE_FOR", "time the admin endpoint raises the log level for by default", durationVar(&c.Log.RaiseFor)},
+		{"LOG_MAX_RAISE", "longest time the admin endpoint may raise the log level for", durationVar(&c.Log.MaxRaise)},
+		{"AUTH_DISABLED", "let every request in with all scopes", boolVar(&c.Auth.Disabled)},
+		{"AUTH_API_KEYS", "API keys, like billing:<sha256 of key>:balance:read|transfer,ops:<sha256>:admin", apiKeysVar(&c.Auth.APIKeys)},
+		{"AUTH_JWT_SECRET", "secret of HS256 tokens", stringVar(&c.Auth.Secret)},
+		{"AUTH_JWT_PUBLIC_KEY_FILE", "PEM file of the RSA public key of RS256 tokens", stringVar(&c.Auth.PublicKeyFile)},
+		{"AUTH_JWT_ISSUER", "required iss claim of tokens", stringVar(&c.Auth.Issuer)},
+		{"AUTH_JWT_AUDIENCE", "required aud claim of tokens", stringVar(&c.Auth.Audience)},
+		{"AUTH_JWT_LEEWAY", "clock skew allowed checking token expiry", durationVar(&c.Auth.Leeway)},
+		{"RATE_LIMITS", "request limits, like transfer:client=10:20,transfer:user=1:5, route:bucket=rate a second:burst", rateLimitsVar(&c.RateLimit)},
+		{"RATE_LIMIT_FAIL_OPEN", "let requests in when Redis is unavailable", boolVar(&c.RateLimit.FailOpen)},
+		{"TRACE_EXPORTER", "exporter of traces: none, stdout or otlp", stringVar(&c.Tracing.Exporter)},
+		{"TRACE_FILE", "file the stdout exporter writes traces to, stdout by default", stringVar(&c.Tracing.File)},
+		{"OTLP_ENDPOINT", "host:port of the OTLP/HTTP collector", stringVar(&c.Tracing.Endpoint)},
+		{"OTLP_INSECURE", "send traces to the collector over plain HTTP", boolVar(&c.Tracing.Insecure)},
+		{"TRACE_SAMPLE_RATIO", "share of recorded traces, from 0 to 1", floatVar(&c.Tracing.SampleRatio)},
+		{"MIGRATE_ON_START", "apply pending migrations on start", boolVar(&c.MigrateOnStart)},
+		{"SHUTDOWN_DELAY", "time between failing readiness and closing the listener on shutdown", durationVar(&c.ShutdownDelay)},
+		{"TIME_TO_SHUTDOWN", "time requests are given to finish on shutdown", durationVar(&c.ShutdownTimeout)},
+	}
+}
+
+// readFile reads a YAML (.yaml, .yml) or TOML (.toml) file into c.
+func (c *Config) readFile(path string) (err error){
+	var decode func([]byte) error
+	switch strings.ToLower(filepath.Ext(path)){
+	case ".yaml", ".yml":
+		decode = func(body []byte) error{
+			return yaml.Unmarshal(body, c)
+		}
+	case ".toml":
+		decode = func(body []byte) error{
+			_, err := toml.Decode(string(body), c)
+			return err
+		}
+	default:
+		return errors.New("config file " + path + " must be .yaml, .yml or .toml")
+	}
+	body, err := ioutil.ReadFile(path)
+	if err != nil{
+		return
+	}
+	err = decode(body)
+	if err != nil{
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return
+}
+
+// Validate returns all problems of the configuration at once.
+func (c *Config) Validate() error{
+	var problems []string
+	check := func(ok bool, problem string){
+		if !ok{
+			problems = append(problems, problem)
+		}
+	}
+	check(c.HTTP.Port != "", "http.port is required")
+	check(c.HTTP.RequestTimeout >= 0, "http.request_timeout must not be negative")
+	routes := make([]string, 0, len(c.HTTP.RequestTimeouts))
+	for route := range c.HTTP.RequestTimeouts{
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes{
+		check(c.HTTP.RequestTimeouts[route] >= 0, "http.request_timeouts." + route + " must not be negative")
+	}
+	check(c.HTTP.HealthTimeout > 0, "http.health_timeout must be positive")
+	check(c.Postgres.URL != "", "postgres.url is required")
+	check(c.Postgres.MaxOpenConns >= 0, "postgres.max_open_conns must not be negative")
+	check(c.Postgres.MaxIdleConns >= 0, "postgres.max_idle_conns must not be negative")
+	check(c.Postgres.ConnMaxLifetime >= 0, "postgres.conn_max_lifetime must not be negative")
+	check(c.Postgres.MaxRetries >= 0, "postgres.max_retries must not be negative")
+	check(c.Redis.URL != "", "redis.url is required")
+	check(c.Redis.MaxIdle >= 0, "redis.max_idle must not be negative")
+	check(c.Redis.MaxActive >= 0, "redis.max_active must not be negative")
+	check(c.Redis.IdleTimeout >= 0, "redis.idle_timeout must not be negative")
+	switch c.Rates.Provider{
+	case "http":
+		check(c.Rates.URL != "", "rates.url is required by the http rate provider")
+		check(c.Rates.Timeout > 0, "rates.timeout must be positive")
+	case "file":
+		check(c.Rates.File != "", "rates.file is required by the file rate provider")
+	case "fixed":
+		check(len(c.Rates.Fixed) > 0, "rates.fixed is required by the fixed rate provider")
+	default:
+		problems = append(problems, "unknown rates.provider " + c.Rates.Provider)
+	}
+	check(c.Rates.Refresh >= 0, "rates.refresh must not be negative")
+	check(c.Reconcile.Period >= 0, "reconcile.period must not be negative")
+	check(c.Reconcile.Dir != "", "reconcile.dir is required")
+	_, err := log.ParseLevel(c.Log.Level)
+	check(err == nil, "unknown log.level " + c.Log.Level)
+	check(c.Log.File != "", "log.file is required")
+	check(c.Log.MaxSize > 0, "log.max_size must be positive")
+	check(c.Log.MaxAgeDays >= 0, "log.max_age_days must not be negative")
+	check(c.Log.MaxBackups >= 0, "log.max_backups must not be negative")
+	check(c.Log.MaxRaise > 0, "log.max_raise must be positive")
+	check(c.Log.RaiseFor > 0 && c.Log.RaiseFor <= c.Log.MaxRaise, "log.raise_for must be positive and at most log.max_raise")
+	if !c.Auth.Disabled{
+		check(len(c.Auth.APIKeys) > 0 || c.Auth.Secret != "" || c.Auth.PublicKeyFile != "",
+			"auth needs api_keys, secret or public_key_file unless it is disabled")
+	}
+	for i, key := range c.Auth.APIKeys{
+		_, err := hex.DecodeString(key.SHA256)
+		check(key.Client != "", "auth.api_keys." + strconv.Itoa(i) + ".client is required")
+		check(err == nil && len(key.SHA256) == 64, "auth.api_keys." + strconv.Itoa(i) + ".sha256 must be 64 hex digits")
+	}
+	check(c.Auth.Leeway >= 0, "auth.leeway must not be negative")
+	checkLimit := func(name string, l Limit){
+		check(l.Rate >= 0, "rate_limit." + name + ".rate must not be negative")
+		check(l.Rate == 0 || l.Burst >= 1, "rate_limit." + name + ".burst must be at least 1")
+	}
+	checkLimit("default.client", c.RateLimit.Default.Client)
+	checkLimit("default.user", c.RateLimit.Default.User)
+	limited := make([]string, 0, len(c.RateLimit.Routes))
+	for route := range c.RateLimit.Routes{
+		limited = append(limited, route)
+	}
+	sort.Strings(limited)
+	for _, route := range limited{
+		checkLimit("routes." + route + ".client", c.RateLimit.Routes[route].Client)
+		checkLimit("routes." + route + ".user", c.RateLimit.Routes[route].User)
+	}
+	switch c.Tracing.Exporter{
+	case "none", "stdout":
+	case "otlp":
+		check(c.Tracing.Endpoint != "", "tracing.endpoint is required by the otlp exporter")
+	default:
+		problems = append(problems, "unknown tracing.exporter " + c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be from 0 to 1")
+	check(c.ShutdownDelay >= 0, "shutdown_delay must not be negative")
+	check(c.ShutdownTimeout > 0, "shutdown_timeout must be positive")
+	if len(problems) > 0{
+		return errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Load registers the flags of the configuration and -config on flags, parses args and returns
+// the validated configuration.
+func Load(flags *flag.FlagSet, args []string) (*Config, error){
+	return load(flags, args, os.LookupEnv)
+}
+
+func load(flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*Config, error){
+	type flagValue struct{
+		Setting int
+		Value string
+	}
+	cfg := Default()
+	settings := cfg.settings()
+	var set []flagValue
+	path := flags.String("config", "", "YAML or TOML config file, CONFIG_FILE by default")
+	for i, s := range settings{
+		i := i
+		flags.Func(s.flag(), s.Usage + " (" + s.Env + ")", func(v string) error{
+			set = append(set, flagValue{Setting: i, Value: v})
+			return nil
+		})
+	}
+	err := flags.Parse(args)
+	if err != nil{
+		return nil, err
+	}
+	if *path == ""{
+		*path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *path != ""{
+		err = cfg.readFile(*path)
+		if err != nil{
+			return nil, err
+		}
+	}
+	for _, s := range settings{
+		v, ok := lookupEnv(s.Env)
+		if !ok || v == ""{
+			continue
+		}
+		if e := s.Set(v); e != nil{
+			return nil, fmt.Errorf("invalid %s: %v", s.Env, e)
+		}
+	}
+	for _, f := range set{
+		s := settings[f.Setting]
+		if e := s.Set(f.Value); e != nil{
+			return nil, fmt.Errorf("invalid -%s: %v", s.flag(), e)
+		}
+	}
+	err = cfg.Validate()
+	if err != nil{
+		return nil, err
+	}
+	return &cfg, nil
+}
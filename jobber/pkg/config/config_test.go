@@ -0,0 +1,196 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(vars map[string]string) func(string) (string, bool){
+	return func(name string) (string, bool){
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+var required = map[string]string{
+	"DATABASE_URL": "postgresql://postgres@localhost:5432/avitojob",
+	"REDIS_URL": "localhost:6379",
+	"CURRENCY_URL": "https://api.exchangeratesapi.io/latest?base=RUB&symbols=",
+	"AUTH_JWT_SECRET": "secret",
+}
+
+func withRequired(vars map[string]string) map[string]string{
+	all := map[string]string{}
+	for k, v := range required{
+		all[k] = v
+	}
+	for k, v := range vars{
+		all[k] = v
+	}
+	return all
+}
+
+func TestLoadDefaults(t *testing.T){
+	cfg, err := load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(required))
+	if err != nil{
+		t.Fatal(err)
+	}
+	def := Default()
+	if cfg.HTTP.Port != def.HTTP.Port || cfg.ShutdownTimeout != def.ShutdownTimeout || cfg.Redis.MaxIdle != def.Redis.MaxIdle{
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if cfg.Postgres.URL != required["DATABASE_URL"]{
+		t.Errorf("unexpected postgres url: %s", cfg.Postgres.URL)
+	}
+}
+
+func TestLoadSources(t *testing.T){
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "jobber.yaml")
+	err := ioutil.WriteFile(yamlFile, []byte(`
+http:
+  port: ":9100"
+  request_timeouts:
+    transfer: 10s
+postgres:
+  max_open_conns: 20
+  max_retries: 5
+rates:
+  provider: fixed
+  fixed:
+    USD: 0.0125
+auth:
+  api_keys:
+    - client: billing
+      sha256: 5627e617b3379cb3e1019a877ce85311ce1e8edb736d87fe9f8355cb4c32668f
+      scopes: [balance:read, transfer]
+rate_limit:
+  routes:
+    getBalance:
+      client: {rate: 5, burst: 10}
+shutdown_timeout: 30
+`), 0666)
+	if err != nil{
+		t.Fatal(err)
+	}
+	tomlFile := filepath.Join(dir, "jobber.toml")
+	err = ioutil.WriteFile(tomlFile, []byte(`
+shutdown_timeout = "15"
+[http]
+port = ":9200"
+[postgres]
+max_open_conns = 30
+[rates]
+provider = "fixed"
+fixed = { EUR = 0.011 }
+`), 0666)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	cfg, err := load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", yamlFile},
+		env(withRequired(map[string]string{"DB_MAX_RETRIES": "7", "TIME_TO_SHUTDOWN": "20", "RATE_LIMITS": "transfer:user=2:4"})))
+	if err != nil{
+		t.Fatal(err)
+	}
+	if cfg.HTTP.Port != ":9100" || cfg.Postgres.MaxOpenConns != 20{
+		t.Errorf("file values are not loaded: %+v", cfg)
+	}
+	if cfg.HTTP.RequestTimeouts["transfer"].Std() != 10*time.Second || cfg.Rates.Fixed["USD"] != 0.0125{
+		t.Errorf("file maps are not loaded: %+v", cfg)
+	}
+	if len(cfg.Auth.APIKeys) != 1 || cfg.Auth.APIKeys[0].Client != "billing" || len(cfg.Auth.APIKeys[0].Scopes) != 2{
+		t.Errorf("file lists are not loaded: %+v", cfg.Auth)
+	}
+	limits := cfg.RateLimit.Routes
+	if limits["getBalance"].Client != (Limit{Rate: 5, Burst: 10}) || limits["transfer"] != (RouteLimit{Client: Limit{Rate: 10, Burst: 20}, User: Limit{Rate: 2, Burst: 4}}){
+		t.Errorf("rate limits are not loaded: %+v", limits)
+	}
+	if cfg.Postgres.MaxRetries != 7 || cfg.ShutdownTimeout.Std() != 20*time.Second{
+		t.Errorf("environment doesn't override the file: %+v", cfg)
+	}
+
+	cfg, err = load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-db-max-open-conns", "40"},
+		env(withRequired(map[string]string{"CONFIG_FILE": tomlFile, "DB_MAX_OPEN_CONNS": "35"})))
+	if err != nil{
+		t.Fatal(err)
+	}
+	if cfg.HTTP.Port != ":9200" || cfg.Rates.Fixed["EUR"] != 0.011 || cfg.ShutdownTimeout.Std() != 15*time.Second{
+		t.Errorf("toml file is not loaded: %+v", cfg)
+	}
+	if cfg.Postgres.MaxOpenConns != 40{
+		t.Errorf("flag doesn't override the environment: %d", cfg.Postgres.MaxOpenConns)
+	}
+}
+
+func TestLoadErrors(t *testing.T){
+	cases := []struct{
+		Env  map[string]string
+		Args []string
+		Err  []string
+	}{
+		{
+			Env: withRequired(map[string]string{"TIME_TO_SHUTDOWN": "ten"}),
+			Err: []string{"invalid TIME_TO_SHUTDOWN"},
+		},
+		{
+			Env: withRequired(nil),
+			Args: []string{"-db-max-retries", "many"},
+			Err: []string{"invalid -db-max-retries"},
+		},
+		{
+			Env: map[string]string{"LOG_LEVEL": "LOUD", "DB_MAX_OPEN_CONNS": "-1", "RATE_PROVIDER": "file"},
+			Err: []string{"postgres.url is required", "redis.url is required", "postgres.max_open_conns must not be negative",
+				"rates.file is required", "unknown log.level LOUD"},
+		},
+		{
+			Env: withRequired(map[string]string{"REQUEST_TIMEOUTS": "transfer"}),
+			Err: []string{"invalid REQUEST_TIMEOUTS"},
+		},
+		{
+			Env: withRequired(map[string]string{"TRACE_EXPORTER": "jaeger", "TRACE_SAMPLE_RATIO": "2"}),
+			Err: []string{"unknown tracing.exporter jaeger", "tracing.sample_ratio must be from 0 to 1"},
+		},
+		{
+			Env: withRequired(map[string]string{"LOG_MAX_SIZE": "0", "LOG_MAX_BACKUPS": "-1", "LOG_RAISE_FOR": "2h"}),
+			Err: []string{"log.max_size must be positive", "log.max_backups must not be negative",
+				"log.raise_for must be positive and at most log.max_raise"},
+		},
+		{
+			Env: withRequired(map[string]string{"AUTH_JWT_SECRET": "", "AUTH_API_KEYS": "billing:abc:transfer,:" + strings.Repeat("0", 64) + ":admin"}),
+			Err: []string{"auth.api_keys.0.sha256 must be 64 hex digits", "auth.api_keys.1.client is required"},
+		},
+		{
+			Env: map[string]string{"DATABASE_URL": "postgresql://localhost", "REDIS_URL": "localhost:6379", "CURRENCY_URL": "http://localhost"},
+			Err: []string{"auth needs api_keys, secret or public_key_file unless it is disabled"},
+		},
+		{
+			Env: withRequired(map[string]string{"RATE_LIMITS": "transfer:client=10"}),
+			Err: []string{"invalid RATE_LIMITS"},
+		},
+		{
+			Env: withRequired(map[string]string{"RATE_LIMITS": "default:user=-1:5,transfer:client=10:0"}),
+			Err: []string{"rate_limit.default.user.rate must not be negative", "rate_limit.routes.transfer.client.burst must be at least 1"},
+		},
+		{
+			Env: withRequired(map[string]string{"CONFIG_FILE": "jobber.ini"}),
+			Err: []string{"must be .yaml, .yml or .toml"},
+		},
+	}
+	for num, c := range cases{
+		_, err := load(flag.NewFlagSet("test", flag.ContinueOnError), c.Args, env(c.Env))
+		if err == nil{
+			t.Errorf("[%d] expected an error", num)
+			continue
+		}
+		for _, e := range c.Err{
+			if !strings.Contains(err.Error(), e){
+				t.Errorf("[%d] error %q doesn't mention %q", num, err, e)
+			}
+		}
+	}
+}
@@ -1,68 +1,211 @@
 package httpServer
 
 import (
-	"fmt"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/fedorkolmykow/avitojob/pkg/auth"
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
+	"github.com/fedorkolmykow/avitojob/pkg/metrics"
 	m "github.com/fedorkolmykow/avitojob/pkg/models"
+	"github.com/fedorkolmykow/avitojob/pkg/ratelimit"
+	"github.com/fedorkolmykow/avitojob/pkg/tracing"
 )
 
 type service interface {
-	ChangeBalance(Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error)
-	Transfer(Req *m.TransferReq) (Resp *m.TransferResp, err error)
-	GetBalance(Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error)
-	GetTransactions(Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error)
+	ChangeBalance(ctx context.Context, Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error)
+	Transfer(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error)
+	Reserve(ctx context.Context, Req *m.ReserveReq) (Resp *m.HoldResp, err error)
+	CaptureHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error)
+	ReleaseHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error)
+	Refund(ctx context.Context, Req *m.RefundReq) (Resp *m.RefundResp, err error)
+	GetBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error)
+	GetBalances(ctx context.Context, Req *m.GetBalancesReq) (Resp *m.GetBalancesResp, err error)
+	GetTransactions(ctx context.Context, Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error)
+	GetTransfer(ctx context.Context, Req *m.GetTransferReq) (Resp *m.Transfer, err error)
+	CheckLedger(ctx context.Context) (Resp *m.LedgerCheck, err error)
 }
 
 type server struct {
 	svc service
+	timeouts Timeouts
+	authn *auth.Authenticator
+	limiter *ratelimit.Limiter
+}
+
+// Timeouts bound the time of handling a request. Endpoints holds the deadlines of routes by their names,
+// other routes get Default. Zero means no deadline.
+type Timeouts struct {
+	Default time.Duration
+	Endpoints map[string]time.Duration
+}
+
+// Route names, used to set deadlines of single endpoints.
+const(
+	RouteChangeBalance = "changeBalance"
+	RouteTransfer = "transfer"
+	RouteReserve = "reserve"
+	RouteCaptureHold = "captureHold"
+	RouteReleaseHold = "releaseHold"
+	RouteGetBalance = "getBalance"
+	RouteGetBalances = "getBalances"
+	RouteGetTransactions = "getTransactions"
+	RouteRefund = "refund"
+	RouteGetTransfer = "getTransfer"
+	RouteCheckLedger = "checkLedger"
+	RouteMetrics = "metrics"
+	RouteLiveness = "liveness"
+	RouteReadiness = "readiness"
+	RouteGetLogLevel = "getLogLevel"
+	RouteRaiseLogLevel = "raiseLogLevel"
+	RouteResetLogLevel = "resetLogLevel"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// APIKeyHeader carries the static API key of a client. Clients with tokens send them as
+// "Authorization: Bearer <JWT>" instead.
+const APIKeyHeader = "X-API-Key"
+
+// routeScopes lists the scopes of routes, any of them lets a client in. Routes which aren't
+// listed, like the probes and metrics, are public. Changes of balances need the scope of their sign
+// and refunds the scopes of the transaction they refund, which is checked by the handlers.
+var routeScopes = map[string][]string{
+	RouteChangeBalance: {auth.ScopeBalanceCredit, auth.ScopeBalanceDebit},
+	RouteTransfer: {auth.ScopeTransfer},
+	RouteReserve: {auth.ScopeBalanceDebit},
+	RouteCaptureHold: {auth.ScopeBalanceDebit},
+	RouteReleaseHold: {auth.ScopeBalanceDebit},
+	RouteRefund: {auth.ScopeBalanceCredit, auth.ScopeBalanceDebit},
+	RouteGetBalance: {auth.ScopeBalanceRead},
+	RouteGetBalances: {auth.ScopeBalanceRead},
+	RouteGetTransactions: {auth.ScopeBalanceRead},
+	RouteGetTransfer: {auth.ScopeBalanceRead},
+	RouteCheckLedger: {auth.ScopeAdmin},
+	RouteGetLogLevel: {auth.ScopeAdmin},
+	RouteRaiseLogLevel: {auth.ScopeAdmin},
+	RouteResetLogLevel: {auth.ScopeAdmin},
+}
+
+// RequestIDHeader carries the id of a request. An id sent by the client is kept, otherwise one is made up.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits the ids taken from clients, so they can't forge log lines.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+func errorStatus(err error) int{
+	switch m.KindOf(err){
+	case m.KindBadRequest:
+		return http.StatusBadRequest
+	case m.KindValidation:
+		return http.StatusUnprocessableEntity
+	case m.KindNotFound:
+		return http.StatusNotFound
+	case m.KindInsufficientFunds, m.KindConflict:
+		return http.StatusConflict
+	case m.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case m.KindUnauthorized:
+		return http.StatusUnauthorized
+	case m.KindForbidden:
+		return http.StatusForbidden
+	case m.KindRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError answers with the status of err and a JSON body carrying its code.
+// Errors without a kind are internal, their text isn't shown to clients.
+func writeError(w http.ResponseWriter, err error){
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled){
+		err = m.NewUnavailableError(m.CodeTimeout, "request timed out", err)
+	}
+	e := &m.Error{Code: m.CodeInternal, Message: "internal error"}
+	errors.As(err, &e)
+	body, mErr := e.MarshalJSON()
+	if mErr != nil{
+		log.Warn(mErr)
+		http.Error(w, e.Message, errorStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(errorStatus(err))
+	_, mErr = w.Write(body)
+	if mErr != nil{
+		log.Warn(mErr)
+	}
 }
 
 func (s *server) HandleChangeBalance(w http.ResponseWriter, r *http.Request){
 	vars := mux.Vars(r)
 	UserID, err := strconv.Atoi(vars["user_id"])
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	req := &m.ChangeBalanceReq{}
 	err = req.UnmarshalJSON(body)
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	req.UserId = UserID
-	log.Trace("Received data: " + fmt.Sprintf("%+v", req))
-	resp, err := s.svc.ChangeBalance(req)
+	req.IdempotencyKey = r.Header.Get(idempotencyKeyHeader)
+	logging.AddFields(r.Context(), req.LogFields())
+	logger(r).Trace("received request")
+	scope := auth.ScopeBalanceCredit
+	if req.Change < 0{
+		scope = auth.ScopeBalanceDebit
+	}
+	err = requireScope(r, scope)
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	resp, err := s.svc.ChangeBalance(r.Context(), req)
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 		return
 	}
 	body, err = resp.MarshalJSON()
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_, err = w.Write(body)
 	if err != nil {
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 	}
 }
 
@@ -70,42 +213,197 @@ func (s *server) HandleTransfer(w http.ResponseWriter, r *http.Request){
 	vars := mux.Vars(r)
 	UserID, err := strconv.Atoi(vars["user_id"])
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	req := &m.TransferReq{}
 	err = req.UnmarshalJSON(body)
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	req.UserId = UserID
+	req.IdempotencyKey = r.Header.Get(idempotencyKeyHeader)
+	logging.AddFields(r.Context(), req.LogFields())
+	logger(r).Trace("received request")
+	resp, err := s.svc.Transfer(r.Context(), req)
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	body, err = resp.MarshalJSON()
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, err)
+	}
+}
+
+func (s *server) HandleReserve(w http.ResponseWriter, r *http.Request){
+	vars := mux.Vars(r)
+	UserID, err := strconv.Atoi(vars["user_id"])
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	req := &m.ReserveReq{}
+	err = req.UnmarshalJSON(body)
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	req.UserId = UserID
-	log.Trace("Received data: " + fmt.Sprintf("%+v", req))
-	resp, err := s.svc.Transfer(req)
+	logging.AddFields(r.Context(), req.LogFields())
+	logger(r).Trace("received request")
+	resp, err := s.svc.Reserve(r.Context(), req)
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 		return
 	}
 	body, err = resp.MarshalJSON()
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_, err = w.Write(body)
 	if err != nil {
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
+	}
+}
+
+func (s *server) HandleCaptureHold(w http.ResponseWriter, r *http.Request){
+	s.handleSettleHold(w, r, s.svc.CaptureHold)
+}
+
+func (s *server) HandleReleaseHold(w http.ResponseWriter, r *http.Request){
+	s.handleSettleHold(w, r, s.svc.ReleaseHold)
+}
+
+func (s *server) handleSettleHold(w http.ResponseWriter, r *http.Request,
+	settle func(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error)){
+	vars := mux.Vars(r)
+	UserID, err := strconv.Atoi(vars["user_id"])
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	HoldID, err := strconv.Atoi(vars["hold_id"])
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	req := &m.HoldReq{UserId: UserID, HoldId: HoldID}
+	logging.AddFields(r.Context(), req.LogFields())
+	logger(r).Trace("received request")
+	resp, err := settle(r.Context(), req)
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	body, err := resp.MarshalJSON()
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, err)
+	}
+}
+
+func (s *server) HandleRefund(w http.ResponseWriter, r *http.Request){
+	vars := mux.Vars(r)
+	UserID, err := strconv.Atoi(vars["user_id"])
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	TransID, err := strconv.Atoi(vars["trans_id"])
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	req := &m.RefundReq{}
+	if len(body) > 0{
+		err = req.UnmarshalJSON(body)
+		if err != nil{
+			logger(r).Warn(err)
+			writeError(w, m.NewBadRequestError(err))
+			return
+		}
+	}
+	req.UserId = UserID
+	req.TransId = TransID
+	req.IdempotencyKey = r.Header.Get(idempotencyKeyHeader)
+	req.Authorize = func(orig *m.Transaction) error{
+		for _, scope := range refundScopes(orig){
+			err := requireScope(r, scope)
+			if err != nil{
+				return err
+			}
+		}
+		return nil
+	}
+	logging.AddFields(r.Context(), req.LogFields())
+	logger(r).Trace("received request")
+	resp, err := s.svc.Refund(r.Context(), req)
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	body, err = resp.MarshalJSON()
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, err)
 	}
 }
 
@@ -113,31 +411,85 @@ func (s *server) HandleBalanceGet(w http.ResponseWriter, r *http.Request){
 	vars := mux.Vars(r)
 	UserID, err := strconv.Atoi(vars["user_id"])
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	req := &m.GetBalanceReq{}
 	req.UserId = UserID
+	req.Wallet = r.FormValue("wallet")
 	req.Currency = r.FormValue("currency")
-	log.Trace("Received data: " + fmt.Sprintf("%+v", req))
-	resp, err := s.svc.GetBalance(req)
+	if date := r.FormValue("rate_date"); date != ""{
+		req.RateDate, err = time.ParseInLocation(m.RateDateLayout, date, time.Local)
+		if err != nil{
+			logger(r).Warn(err)
+			writeError(w, m.NewBadRequestError(err))
+			return
+		}
+	}
+	if asOf := r.FormValue("as_of"); asOf != ""{
+		req.AsOf, err = time.Parse(time.RFC3339, asOf)
+		if err != nil{
+			logger(r).Warn(err)
+			writeError(w, m.NewBadRequestError(err))
+			return
+		}
+	}
+	logging.AddFields(r.Context(), req.LogFields())
+	logger(r).Trace("received request")
+	resp, err := s.svc.GetBalance(r.Context(), req)
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 		return
 	}
 	body, err := resp.MarshalJSON()
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, err)
+	}
+}
+
+func (s *server) HandleBalancesGet(w http.ResponseWriter, r *http.Request){
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	req := &m.GetBalancesReq{}
+	err = req.UnmarshalJSON(body)
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	logging.AddFields(r.Context(), req.LogFields())
+	logger(r).Trace("received request")
+	resp, err := s.svc.GetBalances(r.Context(), req)
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	body, err = resp.MarshalJSON()
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_, err = w.Write(body)
 	if err != nil {
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 	}
 }
 
@@ -145,55 +497,375 @@ func (s *server) HandleTransactionsGet(w http.ResponseWriter, r *http.Request){
 	vars := mux.Vars(r)
 	UserID, err := strconv.Atoi(vars["user_id"])
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	req := &m.GetTransactionsReq{}
 	err = req.UnmarshalJSON(body)
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
 		return
 	}
 	req.UserId = UserID
-	log.Trace("Received data: " + fmt.Sprintf("%+v", req))
-	resp, err := s.svc.GetTransactions(req)
+	logging.AddFields(r.Context(), req.LogFields())
+	logger(r).Trace("received request")
+	resp, err := s.svc.GetTransactions(r.Context(), req)
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 		return
 	}
 	body, err = resp.MarshalJSON()
 	if err != nil{
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_, err = w.Write(body)
 	if err != nil {
-		log.Warn(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger(r).Warn(err)
+		writeError(w, err)
 	}
 }
 
-func NewHTTPServer(svc service) (httpServer *mux.Router) {
+func (s *server) HandleTransferGet(w http.ResponseWriter, r *http.Request){
+	vars := mux.Vars(r)
+	TransferID, err := strconv.Atoi(vars["transfer_id"])
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	req := &m.GetTransferReq{TransferId: TransferID}
+	logging.AddFields(r.Context(), req.LogFields())
+	logger(r).Trace("received request")
+	resp, err := s.svc.GetTransfer(r.Context(), req)
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	body, err := resp.MarshalJSON()
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, err)
+	}
+}
+
+// HandleLedgerCheck answers 200 for a checked ledger, balanced or not, the body tells which.
+func (s *server) HandleLedgerCheck(w http.ResponseWriter, r *http.Request){
+	resp, err := s.svc.CheckLedger(r.Context())
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	body, err := resp.MarshalJSON()
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, err)
+	}
+}
+
+// authenticate returns the client of the API key or the bearer token of the request.
+func (s *server) authenticate(r *http.Request) (*auth.Client, error){
+	if s.authn == nil{
+		return auth.Anonymous, nil
+	}
+	if key := r.Header.Get(APIKeyHeader); key != ""{
+		return s.authn.APIKey(key)
+	}
+	header := r.Header.Get("Authorization")
+	if token := strings.TrimPrefix(header, "Bearer "); token != header{
+		return s.authn.Token(token)
+	}
+	return nil, m.ErrUnauthorized
+}
+
+// withAuth lets in the clients having a scope of the route and puts them in the context of the request.
+func (s *server) withAuth(next http.Handler) http.Handler{
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		scopes, ok := routeScopes[routeName(r)]
+		if !ok{
+			next.ServeHTTP(w, r)
+			return
+		}
+		client, err := s.authenticate(r)
+		if err != nil{
+			logger(r).Warn(err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, err)
+			return
+		}
+		logging.AddFields(r.Context(), log.Fields{"client_id": client.ID})
+		trace.SpanFromContext(r.Context()).SetAttributes(semconv.EnduserIDKey.String(client.ID))
+		for _, scope := range scopes{
+			if client.Has(scope){
+				next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), client)))
+				return
+			}
+		}
+		logger(r).WithField("scopes", scopes).Warn("client lacks the scope of the route")
+		writeError(w, m.ErrForbidden)
+	})
+}
+
+// refundScopes lists the scopes needed to refund orig. A refund of a credit debits the user and
+// the other way round, a refund of a transfer also moves money between users.
+func refundScopes(orig *m.Transaction) []string{
+	scopes := []string{auth.ScopeBalanceCredit}
+	if orig.Change > 0{
+		scopes = []string{auth.ScopeBalanceDebit}
+	}
+	if orig.TransferId != nil{
+		scopes = append(scopes, auth.ScopeTransfer)
+	}
+	return scopes
+}
+
+// requireScope fails unless the client of the request has scope.
+func requireScope(r *http.Request, scope string) error{
+	client, ok := auth.FromContext(r.Context())
+	if !ok || !client.Has(scope){
+		return m.ErrForbidden
+	}
+	return nil
+}
+
+// Headers of rate limits. RateLimit-Reset and Retry-After are in seconds.
+const(
+	RateLimitLimitHeader = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader = "RateLimit-Reset"
+	RetryAfterHeader = "Retry-After"
+)
+
+// ceilSeconds rounds d up to whole seconds, so clients retrying at that time are let in.
+func ceilSeconds(d time.Duration) string{
+	return strconv.FormatInt(int64((d + time.Second - 1) / time.Second), 10)
+}
+
+// withRateLimit takes a token of the route from the buckets of the client and of the user of the path.
+// Denied requests are answered 429 with Retry-After. Routes without a client, the public ones, aren't limited.
+func (s *server) withRateLimit(next http.Handler) http.Handler{
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		client, ok := auth.FromContext(r.Context())
+		if s.limiter == nil || !ok{
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := routeName(r)
+		res, err := s.limiter.Take(r.Context(), route, client.ID, mux.Vars(r)["user_id"])
+		if err != nil{
+			logger(r).Warn(err)
+			writeError(w, err)
+			return
+		}
+		if res.Limit > 0{
+			w.Header().Set(RateLimitLimitHeader, strconv.Itoa(res.Limit))
+			w.Header().Set(RateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+			w.Header().Set(RateLimitResetHeader, ceilSeconds(res.Reset))
+		}
+		if !res.Allowed{
+			metrics.RateLimited.WithLabelValues(route, res.Bucket).Inc()
+			w.Header().Set(RetryAfterHeader, ceilSeconds(res.RetryAfter))
+			writeError(w, m.ErrRateLimited)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withDeadline cancels the context of the request when the deadline of its route passes.
+func (s *server) withDeadline(next http.Handler) http.Handler{
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		timeout := s.timeouts.Default
+		if route := mux.CurrentRoute(r); route != nil{
+			if t, ok := s.timeouts.Endpoints[route.GetName()]; ok{
+				timeout = t
+			}
+		}
+		if timeout > 0{
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusWriter remembers the status code of the response.
+type statusWriter struct{
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int){
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// routeName returns the name of the route of the request.
+func routeName(r *http.Request) string{
+	if current := mux.CurrentRoute(r); current != nil{
+		return current.GetName()
+	}
+	return "unknown"
+}
+
+// withTracing starts the server span of the request, continuing the trace of the traceparent header.
+func withTracing(next http.Handler) http.Handler{
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := routeName(r)
+		ctx, span := tracing.Start(ctx, "HTTP " + route, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPMethodKey.String(r.Method), semconv.HTTPTargetKey.String(r.URL.RequestURI())))
+		defer span.End()
+		if tpl, err := mux.CurrentRoute(r).GetPathTemplate(); err == nil{
+			span.SetAttributes(semconv.HTTPRouteKey.String(tpl))
+		}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(sw.status))
+		if sw.status >= http.StatusInternalServerError{
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+// withMetrics counts requests and their latency by route name.
+func withMetrics(next http.Handler) http.Handler{
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		route := routeName(r)
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(sw, r)
+		metrics.RequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		metrics.Requests.WithLabelValues(route, r.Method, strconv.Itoa(sw.status)).Inc()
+	})
+}
+
+// newRequestID returns a random id of 32 hex digits.
+func newRequestID() string{
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil{
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(id)
+}
+
+// outcome sums up the status of a response for the logs.
+func outcome(status int) string{
+	switch{
+	case status >= http.StatusInternalServerError:
+		return "failed"
+	case status >= http.StatusBadRequest:
+		return "rejected"
+	}
+	return "ok"
+}
+
+// logger returns the logger of the request, carrying its id and route.
+func logger(r *http.Request) *log.Entry{
+	return logging.FromContext(r.Context())
+}
+
+// withLogging gives the request an id and a logger carrying it, and logs the handled request
+// with the fields handlers added, its status, duration and outcome.
+func withLogging(next http.Handler) http.Handler{
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id){
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		fields := log.Fields{"request_id": id, "route": routeName(r), "method": r.Method}
+		if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID(){
+			fields["trace_id"] = sc.TraceID().String()
+		}
+		ctx := logging.NewContext(r.Context(), log.WithFields(fields))
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		entry := logging.FromContext(ctx).WithFields(log.Fields{
+			"status": sw.status,
+			"duration_ms": float64(time.Since(start).Microseconds())/1000,
+			"outcome": outcome(sw.status),
+		})
+		if sw.status >= http.StatusInternalServerError{
+			entry.Error("request handled")
+			return
+		}
+		entry.Info("request handled")
+	})
+}
+
+// NewHTTPServer routes the requests of the API. The admin endpoints are left out without admin,
+// a nil authn lets every request in as auth.Anonymous.
+func NewHTTPServer(svc service, timeouts Timeouts, health *Health, admin *Admin, authn *auth.Authenticator,
+	limiter *ratelimit.Limiter) (httpServer *mux.Router) {
 	router := mux.NewRouter()
-    s := &server{svc: svc}
+    s := &server{svc: svc, timeouts: timeouts, authn: authn, limiter: limiter}
 	router.HandleFunc("/users/{user_id:[0-9]+}/balance", s.HandleChangeBalance).
-		Methods("PATCH")
+		Methods("PATCH").Name(RouteChangeBalance)
 	router.HandleFunc("/users/{user_id:[0-9]+}/balance/transfer", s.HandleTransfer).
-		Methods("PATCH")
+		Methods("PATCH").Name(RouteTransfer)
+	router.HandleFunc("/users/{user_id:[0-9]+}/balance/reserve", s.HandleReserve).
+		Methods("PATCH").Name(RouteReserve)
+	router.HandleFunc("/users/{user_id:[0-9]+}/balance/holds/{hold_id:[0-9]+}/capture", s.HandleCaptureHold).
+		Methods("PATCH").Name(RouteCaptureHold)
+	router.HandleFunc("/users/{user_id:[0-9]+}/balance/holds/{hold_id:[0-9]+}/release", s.HandleReleaseHold).
+		Methods("PATCH").Name(RouteReleaseHold)
 	router.HandleFunc("/users/{user_id:[0-9]+}/balance", s.HandleBalanceGet).
-		Methods("GET")
+		Methods("GET").Name(RouteGetBalance)
+	router.HandleFunc("/balances", s.HandleBalancesGet).
+		Methods("POST").Name(RouteGetBalances)
 	router.HandleFunc("/users/{user_id:[0-9]+}/transactions", s.HandleTransactionsGet).
-		Methods("POST")
+		Methods("POST").Name(RouteGetTransactions)
+	router.HandleFunc("/users/{user_id:[0-9]+}/transactions/{trans_id:[0-9]+}/refund", s.HandleRefund).
+		Methods("PATCH").Name(RouteRefund)
+	router.HandleFunc("/transfers/{transfer_id:[0-9]+}", s.HandleTransferGet).
+		Methods("GET").Name(RouteGetTransfer)
+	router.HandleFunc("/ledger/check", s.HandleLedgerCheck).
+		Methods("GET").Name(RouteCheckLedger)
+	router.Handle("/metrics", promhttp.Handler()).
+		Methods("GET").Name(RouteMetrics)
+	router.HandleFunc("/healthz", health.HandleLiveness).
+		Methods("GET").Name(RouteLiveness)
+	router.HandleFunc("/readyz", health.HandleReadiness).
+		Methods("GET").Name(RouteReadiness)
+	if admin != nil{
+		router.HandleFunc("/admin/log/level", admin.HandleLogLevelGet).
+			Methods("GET").Name(RouteGetLogLevel)
+		router.HandleFunc("/admin/log/level", admin.HandleLogLevelRaise).
+			Methods("PATCH").Name(RouteRaiseLogLevel)
+		router.HandleFunc("/admin/log/level", admin.HandleLogLevelReset).
+			Methods("DELETE").Name(RouteResetLogLevel)
+	}
+	router.Use(withTracing, withLogging, withMetrics, s.withAuth, s.withRateLimit, s.withDeadline)
 	return router
-}
\ No newline at end of file
+}
@@ -1,16 +1,41 @@
 package httpServer
 
 import (
+	"context"
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fedorkolmykow/avitojob/pkg/auth"
+	"github.com/fedorkolmykow/avitojob/pkg/config"
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
+	"github.com/fedorkolmykow/avitojob/pkg/metrics"
 	m "github.com/fedorkolmykow/avitojob/pkg/models"
+	"github.com/fedorkolmykow/avitojob/pkg/ratelimit"
+	"github.com/fedorkolmykow/avitojob/pkg/redis"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 )
 
 const(
@@ -18,16 +43,26 @@ const(
 	transfer
 	getBalance
 	getTransactions
+	reserve
+	captureHold
+	releaseHold
+	refund
+	getTransfer
+	checkLedger
+	getBalances
 )
 
 type correctService struct{
 }
 
 type errorService struct{
+	err error
 }
 
 type TestCase struct {
 	Vars   map[string]string
+	Query   string
+	Header  map[string]string
 	Req     []byte
 	Resp    string
 	Status  int
@@ -40,7 +75,7 @@ func TestHandles(t *testing.T){
 		{
 			Vars:        map[string]string{"user_id":"0"},
 			Req:          []byte(`{"change":200,"comment":"My First","source":"Sberbank"}`),
-			Resp:         `{"user_id":0,"balance":200}`,
+			Resp:         `{"user_id":0,"balance":200,"currency":"RUB"}`,
 			Status:       http.StatusOK,
 			S:            server{svc: &correctService{}},
 			Handle:       changeBalance,
@@ -80,7 +115,7 @@ func TestHandles(t *testing.T){
 		{
 			Vars:        map[string]string{"user_id":"0"},
 			Req:          []byte(`{"change":200,"comment":"My First","target_id":2}`),
-			Resp:         `{"source":{"user_id":0,"balance":0},"target":{"user_id":2,"balance":200}}`,
+			Resp:         `{"transfer_id":1,"rate":1,"source":{"user_id":0,"balance":0,"currency":"RUB"},"target":{"user_id":2,"balance":200,"currency":"RUB"}}`,
 			Status:       http.StatusOK,
 			S:            server{svc: &correctService{}},
 			Handle:       transfer,
@@ -109,10 +144,28 @@ func TestHandles(t *testing.T){
 			S:            server{svc: &errorService{}},
 			Handle:       transfer,
 		},
+		{
+			Vars:        map[string]string{"user_id":"0"},
+			Header:      map[string]string{idempotencyKeyHeader:"key"},
+			Req:          []byte(`{"change":200,"comment":"My First","source":"Sberbank"}`),
+			Resp:         ``,
+			Status:       http.StatusConflict,
+			S:            server{svc: &errorService{err: m.ErrIdempotencyConflict}},
+			Handle:       changeBalance,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0"},
+			Header:      map[string]string{idempotencyKeyHeader:"key"},
+			Req:          []byte(`{"change":200,"comment":"My First","target_id":2}`),
+			Resp:         ``,
+			Status:       http.StatusConflict,
+			S:            server{svc: &errorService{err: m.ErrIdempotencyConflict}},
+			Handle:       transfer,
+		},
 		{
 			Vars:        map[string]string{"user_id":"0"},
 			Req:          []byte(``),
-			Resp:         `{"user_id":0,"balance":0,"currency":"RUB"}`,
+			Resp:         `{"user_id":0,"balance":0,"available":0,"held":0,"currency":"RUB"}`,
 			Status:       http.StatusOK,
 			S:            server{svc: &correctService{}},
 			Handle:       getBalance,
@@ -125,6 +178,55 @@ func TestHandles(t *testing.T){
 			S:            server{svc: &correctService{}},
 			Handle:       getBalance,
 		},
+		{
+			Vars:        map[string]string{"user_id":"0"},
+			Query:        "?as_of=2020-11-01T00:00:00%2B03:00",
+			Req:          []byte(``),
+			Resp:         `{"user_id":0,"balance":150,"available":150,"held":0,"currency":"RUB","as_of":"2020-11-01T00:00:00+03:00"}`,
+			Status:       http.StatusOK,
+			S:            server{svc: &correctService{}},
+			Handle:       getBalance,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0"},
+			Query:        "?as_of=2020-11-01",
+			Req:          []byte(``),
+			Resp:         ``,
+			Status:       http.StatusBadRequest,
+			S:            server{svc: &correctService{}},
+			Handle:       getBalance,
+		},
+		{
+			Req:          []byte(`{"user_ids":[0,1,2],"as_of":"2020-11-01T00:00:00+03:00"}`),
+			Resp:         `{"as_of":"2020-11-01T00:00:00+03:00","currency":"RUB","balances":[{"user_id":0,"balance":150,"available":150,"held":0,"currency":"RUB","as_of":"2020-11-01T00:00:00+03:00"},` +
+				`{"user_id":1,"balance":150,"available":150,"held":0,"currency":"RUB","as_of":"2020-11-01T00:00:00+03:00"}],"missing":[2]}`,
+			Status:       http.StatusOK,
+			S:            server{svc: &correctService{}},
+			Handle:       getBalances,
+		},
+		{
+			Req:          []byte(`{"user_ids":"0"}`),
+			Resp:         ``,
+			Status:       http.StatusBadRequest,
+			S:            server{svc: &correctService{}},
+			Handle:       getBalances,
+		},
+		{
+			Req:          []byte(`{"user_ids":[0]}`),
+			Resp:         ``,
+			Status:       http.StatusInternalServerError,
+			S:            server{svc: &errorService{}},
+			Handle:       getBalances,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0"},
+			Query:        "?currency=USD&rate_date=yesterday",
+			Req:          []byte(``),
+			Resp:         ``,
+			Status:       http.StatusBadRequest,
+			S:            server{svc: &correctService{}},
+			Handle:       getBalance,
+		},
 		{
 			Vars:        map[string]string{"user_id":"0"},
 			Req:          []byte(`{}`),
@@ -136,7 +238,7 @@ func TestHandles(t *testing.T){
 		{
 			Vars:        map[string]string{"user_id":"0"},
 			Req:          []byte(`{"page":1,"per_page":1,"change_sort":false,"time_sort":false}`),
-			Resp:         `{"user_id":0,"transactions":[{"init_balance":0,"change":100,"change_time":"Now","source":"Sberbank","comment":"Test"}]}`,
+			Resp:         `{"user_id":0,"transactions":[{"trans_id":0,"init_balance":0,"change":100,"currency":"RUB","change_time":"2009-11-17T20:34:58Z","source":"Sberbank","comment":"Test"}]}`,
 			Status:       http.StatusOK,
 			S:            server{svc: &correctService{}},
 			Handle:       getTransactions,
@@ -165,21 +267,168 @@ func TestHandles(t *testing.T){
 			S:            server{svc: &errorService{}},
 			Handle:       getTransactions,
 		},
+		{
+			Vars:        map[string]string{"user_id":"0"},
+			Req:          []byte(`{"amount":150,"comment":"Order 1"}`),
+			Resp:         `{"hold_id":1,"user_id":0,"amount":150,"currency":"RUB","status":"held","balance":200,"held":150}`,
+			Status:       http.StatusOK,
+			S:            server{svc: &correctService{}},
+			Handle:       reserve,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0"},
+			Req:          []byte(`Here is error`),
+			Resp:         ``,
+			Status:       http.StatusBadRequest,
+			S:            server{svc: &correctService{}},
+			Handle:       reserve,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0"},
+			Req:          []byte(`{}`),
+			Resp:         ``,
+			Status:       http.StatusInternalServerError,
+			S:            server{svc: &errorService{}},
+			Handle:       reserve,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0", "hold_id":"1"},
+			Req:          []byte(``),
+			Resp:         `{"hold_id":1,"user_id":0,"amount":150,"currency":"RUB","status":"captured","balance":50,"held":0}`,
+			Status:       http.StatusOK,
+			S:            server{svc: &correctService{}},
+			Handle:       captureHold,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0", "hold_id":"Here is error"},
+			Req:          []byte(``),
+			Resp:         ``,
+			Status:       http.StatusBadRequest,
+			S:            server{svc: &correctService{}},
+			Handle:       captureHold,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0", "hold_id":"1"},
+			Req:          []byte(``),
+			Resp:         `{"hold_id":1,"user_id":0,"amount":150,"currency":"RUB","status":"released","balance":200,"held":0}`,
+			Status:       http.StatusOK,
+			S:            server{svc: &correctService{}},
+			Handle:       releaseHold,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0", "hold_id":"1"},
+			Req:          []byte(``),
+			Resp:         ``,
+			Status:       http.StatusInternalServerError,
+			S:            server{svc: &errorService{}},
+			Handle:       releaseHold,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0", "trans_id":"7"},
+			Req:          []byte(`{"amount":50,"comment":"Mistake"}`),
+			Resp:         `{"trans_id":7,"amount":50,"remaining":150,"balances":[{"user_id":0,"balance":150,"currency":"RUB"}]}`,
+			Status:       http.StatusOK,
+			S:            server{svc: &correctService{}},
+			Handle:       refund,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0", "trans_id":"7"},
+			Req:          []byte(``),
+			Resp:         `{"trans_id":7,"amount":200,"remaining":0,"balances":[{"user_id":0,"balance":0,"currency":"RUB"}]}`,
+			Status:       http.StatusOK,
+			S:            server{svc: &correctService{}},
+			Handle:       refund,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0", "trans_id":"Here is error"},
+			Req:          []byte(``),
+			Resp:         ``,
+			Status:       http.StatusBadRequest,
+			S:            server{svc: &correctService{}},
+			Handle:       refund,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0", "trans_id":"7"},
+			Req:          []byte(`Here is error`),
+			Resp:         ``,
+			Status:       http.StatusBadRequest,
+			S:            server{svc: &correctService{}},
+			Handle:       refund,
+		},
+		{
+			Vars:        map[string]string{"user_id":"0", "trans_id":"7"},
+			Req:          []byte(`{}`),
+			Resp:         ``,
+			Status:       http.StatusInternalServerError,
+			S:            server{svc: &errorService{}},
+			Handle:       refund,
+		},
+		{
+			Vars:        map[string]string{"transfer_id":"1"},
+			Req:          []byte(``),
+			Resp:         `{"transfer_id":1,"source_id":0,"target_id":2,"amount":200,"currency":"RUB","target_amount":200,"target_currency":"RUB","rate":1,"comment":"My First","time":"2009-11-17T20:34:58Z",` +
+				`"legs":[{"trans_id":1,"init_balance":200,"change":-200,"currency":"RUB","change_time":"2009-11-17T20:34:58Z","source":"0","comment":"My First","transfer_id":1},` +
+				`{"trans_id":2,"init_balance":0,"change":200,"currency":"RUB","change_time":"2009-11-17T20:34:58Z","source":"0","comment":"My First","transfer_id":1}],"refunds":[]}`,
+			Status:       http.StatusOK,
+			S:            server{svc: &correctService{}},
+			Handle:       getTransfer,
+		},
+		{
+			Vars:        map[string]string{"transfer_id":"Here is error"},
+			Req:          []byte(``),
+			Resp:         ``,
+			Status:       http.StatusBadRequest,
+			S:            server{svc: &correctService{}},
+			Handle:       getTransfer,
+		},
+		{
+			Vars:        map[string]string{"transfer_id":"1"},
+			Req:          []byte(``),
+			Resp:         ``,
+			Status:       http.StatusInternalServerError,
+			S:            server{svc: &errorService{}},
+			Handle:       getTransfer,
+		},
+		{
+			Req:          []byte(``),
+			Resp:         `{"balanced":false,"imbalances":[{"currency":"RUB","sum":1.50},{"entry_id":7,"currency":"RUB","sum":1.50}]}`,
+			Status:       http.StatusOK,
+			S:            server{svc: &correctService{}},
+			Handle:       checkLedger,
+		},
+		{
+			Req:          []byte(``),
+			Resp:         ``,
+			Status:       http.StatusServiceUnavailable,
+			S:            server{svc: &errorService{err: m.NewUnavailableError(m.CodeDatabaseUnavailable, "database is unavailable", nil)}},
+			Handle:       checkLedger,
+		},
 	}
 	log.SetLevel(log.FatalLevel)
 	for num, c := range cases{
 		req := httptest.NewRequest(
 			"NotImportant",
-			"http://localhost",
+			"http://localhost" + c.Query,
 			bytes.NewBuffer(c.Req),
 		)
 		req = mux.SetURLVars(req, c.Vars)
+		req = req.WithContext(auth.NewContext(req.Context(), auth.Anonymous))
+		for k, v := range c.Header{
+			req.Header.Set(k, v)
+		}
 		w := httptest.NewRecorder()
 		switch c.Handle {
 		case changeBalance:     c.S.HandleChangeBalance(w, req)
 		case transfer:     		c.S.HandleTransfer(w, req)
 		case getBalance:  		c.S.HandleBalanceGet(w, req)
 		case getTransactions:   c.S.HandleTransactionsGet(w, req)
+		case reserve:           c.S.HandleReserve(w, req)
+		case captureHold:       c.S.HandleCaptureHold(w, req)
+		case releaseHold:       c.S.HandleReleaseHold(w, req)
+		case refund:            c.S.HandleRefund(w, req)
+		case getTransfer:       c.S.HandleTransferGet(w, req)
+		case checkLedger:       c.S.HandleLedgerCheck(w, req)
+		case getBalances:       c.S.HandleBalancesGet(w, req)
 	}
 
 		if w.Result().StatusCode != c.Status{
@@ -193,30 +442,599 @@ func TestHandles(t *testing.T){
 	}
 }
 
+func TestErrors(t *testing.T){
+	cases := []struct{
+		Err     error
+		Status  int
+		Resp    string
+	}{
+		{
+			Err:     m.NewValidationError("change", "amount is too precise for JPY"),
+			Status:  http.StatusUnprocessableEntity,
+			Resp:    `{"code":"validation_failed","message":"amount is too precise for JPY","fields":[{"field":"change","message":"amount is too precise for JPY"}]}`,
+		},
+		{
+			Err:     m.ErrWalletNotFound,
+			Status:  http.StatusNotFound,
+			Resp:    `{"code":"wallet_not_found","message":"user doesn't have the wallet"}`,
+		},
+		{
+			Err:     m.ErrInsufficientFunds,
+			Status:  http.StatusConflict,
+			Resp:    `{"code":"insufficient_funds","message":"insufficient funds"}`,
+		},
+		{
+			Err:     fmt.Errorf("transfer: %w", m.NewUnavailableError(m.CodeDatabaseUnavailable, "database is unavailable", errors.New("connection refused"))),
+			Status:  http.StatusServiceUnavailable,
+			Resp:    `{"code":"database_unavailable","message":"database is unavailable"}`,
+		},
+		{
+			Err:     fmt.Errorf("select balance: %w", context.DeadlineExceeded),
+			Status:  http.StatusServiceUnavailable,
+			Resp:    `{"code":"timeout","message":"request timed out"}`,
+		},
+		{
+			Err:     errors.New("pq: relation \"users\" does not exist"),
+			Status:  http.StatusInternalServerError,
+			Resp:    `{"code":"internal_error","message":"internal error"}`,
+		},
+	}
+	for num, c := range cases{
+		req := httptest.NewRequest("NotImportant", "http://localhost", bytes.NewBufferString(`{"change":1}`))
+		req = mux.SetURLVars(req, map[string]string{"user_id":"0"})
+		req = req.WithContext(auth.NewContext(req.Context(), auth.Anonymous))
+		w := httptest.NewRecorder()
+		s := server{svc: &errorService{err: c.Err}}
+		s.HandleChangeBalance(w, req)
+		if w.Result().StatusCode != c.Status{
+			t.Errorf("[%d] unexpected status: %d, expected: %d", num, w.Result().StatusCode, c.Status)
+		}
+		if w.Body.String() != c.Resp{
+			t.Errorf("[%d] unexpected result:\n%s\nexpected:\n%s ", num, w.Body.String(), c.Resp)
+		}
+	}
+}
+
+// deadlineService records the deadline of the context it was called with.
+type deadlineService struct{
+	correctService
+	deadline time.Time
+}
+
+func (s *deadlineService) GetBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error){
+	s.deadline, _ = ctx.Deadline()
+	return s.correctService.GetBalance(ctx, Req)
+}
+
+func (s *deadlineService) GetTransfer(ctx context.Context, Req *m.GetTransferReq) (Resp *m.Transfer, err error){
+	s.deadline, _ = ctx.Deadline()
+	return s.correctService.GetTransfer(ctx, Req)
+}
+
+func TestEndpointDeadlines(t *testing.T){
+	svc := &deadlineService{}
+	router := NewHTTPServer(svc, Timeouts{
+		Default: time.Minute,
+		Endpoints: map[string]time.Duration{RouteGetBalance: time.Second},
+	}, NewHealth(time.Second, nil), nil, nil, nil)
+	cases := []struct{
+		Url     string
+		Timeout time.Duration
+	}{
+		{Url: "http://localhost/users/1/balance", Timeout: time.Second},
+		{Url: "http://localhost/transfers/1", Timeout: time.Minute},
+	}
+	for num, c := range cases{
+		svc.deadline = time.Time{}
+		start := time.Now()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", c.Url, nil))
+		if w.Result().StatusCode != http.StatusOK{
+			t.Errorf("[%d] unexpected status: %d", num, w.Result().StatusCode)
+		}
+		if svc.deadline.Before(start.Add(c.Timeout)) || svc.deadline.After(time.Now().Add(c.Timeout)){
+			t.Errorf("[%d] unexpected deadline: %v, expected in %v", num, svc.deadline.Sub(start), c.Timeout)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T){
+	router := NewHTTPServer(&correctService{}, Timeouts{}, NewHealth(time.Second, nil), nil, nil, nil)
+	requests := metrics.Requests.WithLabelValues(RouteGetTransfer, "GET", "200")
+	before := testutil.ToFloat64(requests)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/transfers/1", nil))
+	if got := testutil.ToFloat64(requests) - before; got != 1{
+		t.Errorf("unexpected number of counted requests: %v, expected: 1", got)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/metrics", nil))
+	if w.Result().StatusCode != http.StatusOK{
+		t.Fatalf("unexpected status: %d", w.Result().StatusCode)
+	}
+	body := w.Body.String()
+	for _, name := range []string{
+		`jobber_http_requests_total{code="200",method="GET",route="getTransfer"}`,
+		`jobber_http_request_duration_seconds_bucket{method="GET",route="getTransfer"`,
+	}{
+		if !strings.Contains(body, name){
+			t.Errorf("metrics don't contain %s", name)
+		}
+	}
+}
+
+func TestTracing(t *testing.T){
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	router := NewHTTPServer(&correctService{}, Timeouts{}, NewHealth(time.Second, nil), nil, nil, nil)
+	req := httptest.NewRequest("GET", "http://localhost/transfers/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1{
+		t.Fatalf("unexpected number of spans: %d, expected: 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "HTTP " + RouteGetTransfer || span.SpanKind() != trace.SpanKindServer{
+		t.Errorf("unexpected span: %s %v", span.Name(), span.SpanKind())
+	}
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		span.Parent().SpanID().String() != "00f067aa0ba902b7"{
+		t.Errorf("span doesn't continue the trace of traceparent: %v, parent %v", span.SpanContext(), span.Parent())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range span.Attributes(){
+		attrs[attr.Key] = attr.Value
+	}
+	if attrs[semconv.HTTPRouteKey].AsString() != "/transfers/{transfer_id:[0-9]+}" || attrs[semconv.HTTPStatusCodeKey].AsInt64() != 200{
+		t.Errorf("unexpected attributes: %v", span.Attributes())
+	}
+}
+
+func TestRequestLogging(t *testing.T){
+	hook := logtest.NewGlobal()
+	log.SetLevel(log.InfoLevel)
+	defer log.SetLevel(log.FatalLevel)
+	cases := []struct{
+		RequestID string
+		Service   service
+		Fields    log.Fields
+		Level     log.Level
+	}{
+		{
+			RequestID: "req-1",
+			Service: &correctService{},
+			Fields: log.Fields{"request_id": "req-1", "route": RouteChangeBalance, "method": "PATCH",
+				"user_id": 1, "amount": m.Money(40000), "status": http.StatusOK, "outcome": "ok"},
+			Level: log.InfoLevel,
+		},
+		{
+			RequestID: "forged\nline",
+			Service: &errorService{err: m.ErrInsufficientFunds},
+			Fields: log.Fields{"route": RouteChangeBalance, "user_id": 1, "status": http.StatusConflict, "outcome": "rejected"},
+			Level: log.InfoLevel,
+		},
+		{
+			Service: &errorService{},
+			Fields: log.Fields{"route": RouteChangeBalance, "user_id": 1, "status": http.StatusInternalServerError, "outcome": "failed"},
+			Level: log.ErrorLevel,
+		},
+	}
+	for num, c := range cases{
+		hook.Reset()
+		router := NewHTTPServer(c.Service, Timeouts{}, NewHealth(time.Second, nil), nil, nil, nil)
+		req := httptest.NewRequest("PATCH", "http://localhost/users/1/balance", strings.NewReader(`{"change":400}`))
+		if c.RequestID != ""{
+			req.Header.Set(RequestIDHeader, c.RequestID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		id := w.Header().Get(RequestIDHeader)
+		if c.Fields["request_id"] == nil && !requestIDPattern.MatchString(id) || c.Fields["request_id"] != nil && id != c.Fields["request_id"]{
+			t.Errorf("[%d] unexpected request id: %q", num, id)
+		}
+		entry := hook.LastEntry()
+		if entry == nil || entry.Message != "request handled"{
+			t.Fatalf("[%d] request isn't logged: %v", num, entry)
+		}
+		if entry.Level != c.Level{
+			t.Errorf("[%d] unexpected level: %v, expected: %v", num, entry.Level, c.Level)
+		}
+		if entry.Data["request_id"] != id{
+			t.Errorf("[%d] unexpected request_id: %v, expected: %s", num, entry.Data["request_id"], id)
+		}
+		for field, value := range c.Fields{
+			if entry.Data[field] != value{
+				t.Errorf("[%d] unexpected %s: %v, expected: %v", num, field, entry.Data[field], value)
+			}
+		}
+		if _, ok := entry.Data["duration_ms"].(float64); !ok{
+			t.Errorf("[%d] duration isn't logged: %v", num, entry.Data)
+		}
+	}
+}
+
+func TestAdmin(t *testing.T){
+	defer log.SetLevel(log.FatalLevel)
+	levels := logging.NewLevelSwitch(log.FatalLevel)
+	router := NewHTTPServer(&correctService{}, Timeouts{}, NewHealth(time.Second, nil),
+		NewAdmin(levels, time.Minute, time.Hour), authenticator(t), nil)
+	cases := []struct{
+		Method string
+		Key    string
+		Body   string
+		Status int
+		Level  log.Level
+		Raised bool
+	}{
+		{Method: "PATCH", Status: http.StatusUnauthorized, Level: log.FatalLevel},
+		{Method: "PATCH", Key: "reader-key", Status: http.StatusForbidden, Level: log.FatalLevel},
+		{Method: "PATCH", Key: "admin-key", Status: http.StatusOK, Level: log.TraceLevel, Raised: true},
+		{Method: "GET", Key: "admin-key", Status: http.StatusOK, Level: log.TraceLevel, Raised: true},
+		{Method: "DELETE", Key: "admin-key", Status: http.StatusOK, Level: log.FatalLevel},
+		{Method: "PATCH", Key: "admin-key", Body: `{"level":"debug","seconds":60}`, Status: http.StatusOK, Level: log.DebugLevel, Raised: true},
+		{Method: "PATCH", Key: "admin-key", Body: `{"level":"loud"}`, Status: http.StatusUnprocessableEntity, Level: log.DebugLevel},
+		{Method: "PATCH", Key: "admin-key", Body: `{"seconds":7200}`, Status: http.StatusUnprocessableEntity, Level: log.DebugLevel},
+		{Method: "PATCH", Key: "admin-key", Body: `{"level":"panic"}`, Status: http.StatusUnprocessableEntity, Level: log.DebugLevel},
+		{Method: "PATCH", Key: "admin-key", Body: `{"level":"fatal"}`, Status: http.StatusOK, Level: log.FatalLevel, Raised: true},
+	}
+	for num, c := range cases{
+		req := httptest.NewRequest(c.Method, "http://localhost/admin/log/level", strings.NewReader(c.Body))
+		if c.Key != ""{
+			req.Header.Set(APIKeyHeader, c.Key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Result().StatusCode != c.Status{
+			t.Errorf("[%d] unexpected status: %d, expected: %d", num, w.Result().StatusCode, c.Status)
+		}
+		if log.GetLevel() != c.Level{
+			t.Errorf("[%d] unexpected level: %v, expected: %v", num, log.GetLevel(), c.Level)
+		}
+		if c.Status != http.StatusOK{
+			continue
+		}
+		resp := &m.LogLevel{}
+		err := resp.UnmarshalJSON(w.Body.Bytes())
+		if err != nil || resp.Level != c.Level.String() || resp.Base != "fatal" || (resp.Until != nil) != c.Raised{
+			t.Errorf("[%d] unexpected response: %s", num, w.Body.String())
+		}
+	}
+
+	router = NewHTTPServer(&correctService{}, Timeouts{}, NewHealth(time.Second, nil), nil, nil, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/admin/log/level", nil))
+	if w.Result().StatusCode != http.StatusNotFound{
+		t.Errorf("admin endpoints are served without admin: %d", w.Result().StatusCode)
+	}
+}
+
+const jwtSecret = "jwt-secret"
+
+// authenticator knows the keys reader-key, credit-key, debit-key and admin-key of clients with the scopes
+// in their names, and HS256 tokens signed with jwtSecret.
+func authenticator(t *testing.T) *auth.Authenticator{
+	key := func(client string, scopes ...string) config.APIKey{
+		sum := sha256.Sum256([]byte(client + "-key"))
+		return config.APIKey{Client: client, SHA256: hex.EncodeToString(sum[:]), Scopes: scopes}
+	}
+	authn, err := auth.New(config.Auth{
+		APIKeys: []config.APIKey{
+			key("reader", auth.ScopeBalanceRead),
+			key("credit", auth.ScopeBalanceCredit),
+			key("debit", auth.ScopeBalanceDebit),
+			key("admin", auth.ScopeAdmin),
+		},
+		Secret: jwtSecret,
+	})
+	if err != nil{
+		t.Fatal(err)
+	}
+	return authn
+}
+
+func hs256(claims string) string{
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(jwtSecret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuth(t *testing.T){
+	router := NewHTTPServer(&correctService{}, Timeouts{}, NewHealth(time.Second, nil), nil, authenticator(t), nil)
+	exp := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	cases := []struct{
+		Method  string
+		Url     string
+		Body    string
+		Header  map[string]string
+		Status  int
+	}{
+		{Method: "GET", Url: "/healthz", Status: http.StatusOK},
+		{Method: "GET", Url: "/users/1/balance", Status: http.StatusUnauthorized},
+		{Method: "GET", Url: "/users/1/balance", Header: map[string]string{APIKeyHeader: "unknown-key"}, Status: http.StatusUnauthorized},
+		{Method: "GET", Url: "/users/1/balance", Header: map[string]string{APIKeyHeader: "reader-key"}, Status: http.StatusOK},
+		{Method: "PATCH", Url: "/users/1/balance", Body: `{"change":100}`, Header: map[string]string{APIKeyHeader: "reader-key"}, Status: http.StatusForbidden},
+		{Method: "PATCH", Url: "/users/1/balance", Body: `{"change":100}`, Header: map[string]string{APIKeyHeader: "credit-key"}, Status: http.StatusOK},
+		{Method: "PATCH", Url: "/users/1/balance", Body: `{"change":-100}`, Header: map[string]string{APIKeyHeader: "credit-key"}, Status: http.StatusForbidden},
+		{Method: "GET", Url: "/ledger/check", Header: map[string]string{APIKeyHeader: "credit-key"}, Status: http.StatusForbidden},
+		// a refund of a credit debits the user
+		{Method: "PATCH", Url: "/users/1/transactions/4/refund", Header: map[string]string{APIKeyHeader: "credit-key"}, Status: http.StatusForbidden},
+		{Method: "PATCH", Url: "/users/1/transactions/4/refund", Header: map[string]string{APIKeyHeader: "debit-key"}, Status: http.StatusOK},
+		{
+			Method: "PATCH", Url: "/users/1/balance/transfer", Body: `{"change":100,"target_id":2}`,
+			Header: map[string]string{"Authorization": "Bearer " + hs256(`{"sub":"shop","scope":"transfer balance:read","exp":` + exp + `}`)},
+			Status: http.StatusOK,
+		},
+		{
+			Method: "PATCH", Url: "/users/1/balance/transfer", Body: `{"change":100,"target_id":2}`,
+			Header: map[string]string{"Authorization": "Bearer " + hs256(`{"sub":"shop","scope":"transfer","exp":1}`)},
+			Status: http.StatusUnauthorized,
+		},
+	}
+	for num, c := range cases{
+		req := httptest.NewRequest(c.Method, "http://localhost" + c.Url, strings.NewReader(c.Body))
+		for k, v := range c.Header{
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Result().StatusCode != c.Status{
+			t.Errorf("[%d] unexpected status: %d, expected: %d, body: %s", num, w.Result().StatusCode, c.Status, w.Body.String())
+		}
+		if c.Status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer"{
+			t.Errorf("[%d] WWW-Authenticate isn't set", num)
+		}
+	}
+}
+
+// bucketStore keeps token buckets which are never refilled.
+type bucketStore struct{
+	tokens map[string]float64
+}
+
+func (s *bucketStore) TakeTokens(ctx context.Context, buckets []redis.Bucket) (bool, []float64, error){
+	allowed := true
+	tokens := make([]float64, len(buckets))
+	for i, b := range buckets{
+		n, ok := s.tokens[b.Key]
+		if !ok{
+			n = float64(b.Burst)
+		}
+		allowed = allowed && n >= 1
+		tokens[i] = n
+	}
+	for i, b := range buckets{
+		if allowed{
+			tokens[i]--
+		}
+		s.tokens[b.Key] = tokens[i]
+	}
+	return allowed, tokens, nil
+}
+
+func TestRateLimit(t *testing.T){
+	limiter := ratelimit.New(&bucketStore{tokens: map[string]float64{}}, config.RateLimit{
+		Default: config.RouteLimit{Client: config.Limit{Rate: 1, Burst: 3}, User: config.Limit{Rate: 1, Burst: 2}},
+	})
+	router := NewHTTPServer(&correctService{}, Timeouts{}, NewHealth(time.Second, nil), nil, authenticator(t), limiter)
+	limited := testutil.ToFloat64(metrics.RateLimited.WithLabelValues(RouteGetBalance, ratelimit.BucketUser))
+	cases := []struct{
+		Url        string
+		Status     int
+		Limit      string
+		Remaining  string
+		Reset      string
+		RetryAfter string
+	}{
+		{Url: "/users/1/balance", Status: http.StatusOK, Limit: "2", Remaining: "1", Reset: "1"},
+		{Url: "/users/1/balance", Status: http.StatusOK, Limit: "2", Remaining: "0", Reset: "2"},
+		{Url: "/users/1/balance", Status: http.StatusTooManyRequests, Limit: "2", Remaining: "0", Reset: "2", RetryAfter: "1"},
+		// the denied request took no token of the client
+		{Url: "/users/2/balance", Status: http.StatusOK, Limit: "3", Remaining: "0", Reset: "3"},
+		{Url: "/users/3/balance", Status: http.StatusTooManyRequests, Limit: "3", Remaining: "0", Reset: "3", RetryAfter: "1"},
+		{Url: "/healthz", Status: http.StatusOK},
+	}
+	for num, c := range cases{
+		req := httptest.NewRequest("GET", "http://localhost" + c.Url, nil)
+		req.Header.Set(APIKeyHeader, "reader-key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Result().StatusCode != c.Status{
+			t.Errorf("[%d] unexpected status: %d, expected: %d, body: %s", num, w.Result().StatusCode, c.Status, w.Body.String())
+		}
+		headers := []string{w.Header().Get(RateLimitLimitHeader), w.Header().Get(RateLimitRemainingHeader),
+			w.Header().Get(RateLimitResetHeader), w.Header().Get(RetryAfterHeader)}
+		expected := []string{c.Limit, c.Remaining, c.Reset, c.RetryAfter}
+		if !reflect.DeepEqual(headers, expected){
+			t.Errorf("[%d] unexpected headers: %v, expected: %v", num, headers, expected)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.RateLimited.WithLabelValues(RouteGetBalance, ratelimit.BucketUser)); got != limited + 1{
+		t.Errorf("unexpected rate limited requests of users: %v, expected: %v", got, limited + 1)
+	}
+}
+
+type pinger struct{
+	err error
+	delay time.Duration
+}
+
+func (p pinger) Ping(ctx context.Context) error{
+	select{
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.delay):
+		return p.err
+	}
+}
+
+func TestHealth(t *testing.T){
+	healthy := map[string]Pinger{"postgres": pinger{}, "redis": pinger{}}
+	cases := []struct{
+		Dependencies map[string]Pinger
+		ShuttingDown bool
+		Url          string
+		Resp         string
+		Status       int
+	}{
+		{
+			Url: "http://localhost/healthz",
+			Resp: `{"status":"ok"}`,
+			Status: http.StatusOK,
+		},
+		{
+			Dependencies: healthy,
+			Url: "http://localhost/readyz",
+			Resp: `{"status":"ok","dependencies":{"postgres":{"status":"ok"},"redis":{"status":"ok"}}}`,
+			Status: http.StatusOK,
+		},
+		{
+			Dependencies: map[string]Pinger{
+				"postgres": pinger{err: errors.New("connection refused")},
+				"redis": pinger{delay: time.Minute},
+			},
+			Url: "http://localhost/readyz",
+			Resp: `{"status":"unavailable","dependencies":{"postgres":{"status":"unavailable","error":"connection refused"},` +
+				`"redis":{"status":"unavailable","error":"context deadline exceeded"}}}`,
+			Status: http.StatusServiceUnavailable,
+		},
+		{
+			Dependencies: healthy,
+			ShuttingDown: true,
+			Url: "http://localhost/readyz",
+			Resp: `{"status":"shutting_down","dependencies":{}}`,
+			Status: http.StatusServiceUnavailable,
+		},
+		{
+			Dependencies: healthy,
+			ShuttingDown: true,
+			Url: "http://localhost/healthz",
+			Resp: `{"status":"ok"}`,
+			Status: http.StatusOK,
+		},
+	}
+	for num, c := range cases{
+		health := NewHealth(10*time.Millisecond, c.Dependencies)
+		if c.ShuttingDown{
+			health.Shutdown()
+		}
+		router := NewHTTPServer(&correctService{}, Timeouts{}, health, nil, nil, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", c.Url, nil))
+		if w.Result().StatusCode != c.Status{
+			t.Errorf("[%d] unexpected status: %d, expected: %d", num, w.Result().StatusCode, c.Status)
+		}
+		got, expected := &m.Readiness{}, &m.Readiness{}
+		if got.UnmarshalJSON(w.Body.Bytes()) != nil || expected.UnmarshalJSON([]byte(c.Resp)) != nil ||
+			!reflect.DeepEqual(got, expected){
+			t.Errorf("[%d] unexpected response: %s, expected: %s", num, w.Body.String(), c.Resp)
+		}
+	}
+}
+
 //correctService
-func (s *correctService)  ChangeBalance(Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error) {
+func (s *correctService)  ChangeBalance(ctx context.Context, Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error) {
 	return &m.ChangeBalanceResp{
-		UserId:  Req.UserId,
-		Balance: Req.Change,
+		UserId:   Req.UserId,
+		Balance:  Req.Change,
+		Currency: "RUB",
 	}, nil
 }
 
 
-func (s *correctService) Transfer(Req *m.TransferReq) (Resp *m.TransferResp, err error){
+func (s *correctService) Transfer(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error){
 	return &m.TransferResp{
+		TransferId: 1,
+		Rate:       1,
 		Source: m.ChangeBalanceResp{
-			UserId:  Req.UserId,
-			Balance: 0,
+			UserId:   Req.UserId,
+			Balance:  0,
+			Currency: "RUB",
 		},
 		Target: m.ChangeBalanceResp{
-			UserId:  Req.TargetId,
-			Balance: Req.Change,
+			UserId:   Req.TargetId,
+			Balance:  Req.Change,
+			Currency: "RUB",
 		},
 	}, nil
 }
 
 
-func (s *correctService) GetBalance(Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error){
+func (s *correctService) Reserve(ctx context.Context, Req *m.ReserveReq) (Resp *m.HoldResp, err error){
+	return &m.HoldResp{
+		HoldId:  1,
+		UserId:  Req.UserId,
+		Amount:  Req.Amount,
+		Currency: "RUB",
+		Status:  m.HoldHeld,
+		Balance: 20000,
+		Held:    Req.Amount,
+	}, nil
+}
+
+
+func (s *correctService) CaptureHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error){
+	return &m.HoldResp{
+		HoldId:  Req.HoldId,
+		UserId:  Req.UserId,
+		Amount:  15000,
+		Currency: "RUB",
+		Status:  m.HoldCaptured,
+		Balance: 5000,
+	}, nil
+}
+
+
+func (s *correctService) ReleaseHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error){
+	return &m.HoldResp{
+		HoldId:  Req.HoldId,
+		UserId:  Req.UserId,
+		Amount:  15000,
+		Currency: "RUB",
+		Status:  m.HoldReleased,
+		Balance: 20000,
+	}, nil
+}
+
+
+func (s *correctService) Refund(ctx context.Context, Req *m.RefundReq) (Resp *m.RefundResp, err error){
+	if Req.Authorize != nil{
+		// the transactions of correctService are credits
+		err = Req.Authorize(&m.Transaction{TransId: Req.TransId, UserId: Req.UserId, Change: 20000, Currency: "RUB"})
+		if err != nil{
+			return
+		}
+	}
+	amount := Req.Amount
+	if amount == 0{
+		amount = 20000
+	}
+	return &m.RefundResp{
+		TransId:   Req.TransId,
+		Amount:    amount,
+		Remaining: 20000 - amount,
+		Balances:  []m.ChangeBalanceResp{{UserId: Req.UserId, Balance: 20000 - amount, Currency: "RUB"}},
+	}, nil
+}
+
+
+func (s *correctService) GetBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error){
+	if !Req.AsOf.IsZero(){
+		return &m.GetBalanceResp{
+			UserId:    Req.UserId,
+			Balance:   15000,
+			Available: 15000,
+			Currency:  "RUB",
+			AsOf:      &Req.AsOf,
+		}, nil
+	}
 	return &m.GetBalanceResp{
 		UserId:   0,
 		Balance:  0,
@@ -225,7 +1043,26 @@ func (s *correctService) GetBalance(Req *m.GetBalanceReq) (Resp *m.GetBalanceRes
 }
 
 
-func (s *correctService) GetTransactions(Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error){
+func (s *correctService) GetBalances(ctx context.Context, Req *m.GetBalancesReq) (Resp *m.GetBalancesResp, err error){
+	Resp = &m.GetBalancesResp{AsOf: Req.AsOf, Currency: "RUB", Missing: []int{}}
+	for _, id := range Req.UserIds{
+		if id > 1{
+			Resp.Missing = append(Resp.Missing, id)
+			continue
+		}
+		Resp.Balances = append(Resp.Balances, m.GetBalanceResp{
+			UserId:    id,
+			Balance:   15000,
+			Available: 15000,
+			Currency:  "RUB",
+			AsOf:      &Req.AsOf,
+		})
+	}
+	return
+}
+
+
+func (s *correctService) GetTransactions(ctx context.Context, Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error){
 	return &m.GetTransactionsResp{
 		UserId:       Req.UserId,
 		Transactions: []m.Transaction{
@@ -233,8 +1070,9 @@ func (s *correctService) GetTransactions(Req *m.GetTransactionsReq) (Resp *m.Get
 				TransId:        0,
 				UserId:         Req.UserId,
 				InitialBalance: 0,
-				Change:         100,
-				ChangeTime:     "Now",
+				Change:         10000,
+				Currency:       "RUB",
+				ChangeTime:     time.Date(2009, 11, 17, 20, 34, 58, 0, time.UTC),
 				Source:         "Sberbank",
 				Comment:        "Test",
 			},
@@ -243,22 +1081,101 @@ func (s *correctService) GetTransactions(Req *m.GetTransactionsReq) (Resp *m.Get
 }
 
 
+func (s *correctService) GetTransfer(ctx context.Context, Req *m.GetTransferReq) (Resp *m.Transfer, err error){
+	now := time.Date(2009, 11, 17, 20, 34, 58, 0, time.UTC)
+	return &m.Transfer{
+		TransferId: Req.TransferId,
+		SourceId:   0,
+		TargetId:   2,
+		Amount:     20000,
+		Currency:   "RUB",
+		TargetAmount: 20000,
+		TargetCurrency: "RUB",
+		Rate:       1,
+		Comment:    "My First",
+		Time:       now,
+		Legs:       []m.Transaction{
+			{TransId: 1, UserId: 0, InitialBalance: 20000, Change: -20000, Currency: "RUB", ChangeTime: now,
+				Source: "0", Comment: "My First", TransferId: &Req.TransferId},
+			{TransId: 2, UserId: 2, InitialBalance: 0, Change: 20000, Currency: "RUB", ChangeTime: now,
+				Source: "0", Comment: "My First", TransferId: &Req.TransferId},
+		},
+		Refunds:    []m.Transaction{},
+	}, nil
+}
+
+
+func (s *correctService) CheckLedger(ctx context.Context) (Resp *m.LedgerCheck, err error){
+	entryId := 7
+	return &m.LedgerCheck{
+		Balanced:   false,
+		Imbalances: []m.Imbalance{
+			{Currency: "RUB", Sum: 150},
+			{EntryId: &entryId, Currency: "RUB", Sum: 150},
+		},
+	}, nil
+}
+
+
 //errorService
-func (s *errorService)  ChangeBalance(Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error) {
-	return nil, errors.New("test error")
+func (s *errorService) error() error {
+	if s.err != nil{
+		return s.err
+	}
+	return errors.New("test error")
+}
+
+
+func (s *errorService)  ChangeBalance(ctx context.Context, Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error) {
+	return nil, s.error()
 }
 
 
-func (s *errorService) Transfer(Req *m.TransferReq) (Resp *m.TransferResp, err error){
-	return nil, errors.New("test error")
+func (s *errorService) Transfer(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error){
+	return nil, s.error()
 }
 
 
-func (s *errorService) GetBalance(Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error){
-	return nil, errors.New("test error")
+func (s *errorService) Reserve(ctx context.Context, Req *m.ReserveReq) (Resp *m.HoldResp, err error){
+	return nil, s.error()
 }
 
 
-func (s *errorService) GetTransactions(Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error){
-	return nil, errors.New("test error")
-}
\ No newline at end of file
+func (s *errorService) CaptureHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error){
+	return nil, s.error()
+}
+
+
+func (s *errorService) ReleaseHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error){
+	return nil, s.error()
+}
+
+
+func (s *errorService) Refund(ctx context.Context, Req *m.RefundReq) (Resp *m.RefundResp, err error){
+	return nil, s.error()
+}
+
+
+func (s *errorService) GetBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error){
+	return nil, s.error()
+}
+
+
+func (s *errorService) GetBalances(ctx context.Context, Req *m.GetBalancesReq) (Resp *m.GetBalancesResp, err error){
+	return nil, s.error()
+}
+
+
+func (s *errorService) GetTransactions(ctx context.Context, Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error){
+	return nil, s.error()
+}
+
+
+func (s *errorService) GetTransfer(ctx context.Context, Req *m.GetTransferReq) (Resp *m.Transfer, err error){
+	return nil, s.error()
+}
+
+
+func (s *errorService) CheckLedger(ctx context.Context) (Resp *m.LedgerCheck, err error){
+	return nil, s.error()
+}
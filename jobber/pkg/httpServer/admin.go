@@ -0,0 +1,105 @@
+package httpServer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+)
+
+// Admin serves the endpoints of operators, clients with the admin scope.
+type Admin struct{
+	levels *logging.LevelSwitch
+	// raiseFor is the time a level is raised for by default, maxRaise is the longest allowed
+	raiseFor time.Duration
+	maxRaise time.Duration
+}
+
+func (a *Admin) writeLevel(w http.ResponseWriter, r *http.Request){
+	level, base, until := a.levels.Level()
+	resp := &m.LogLevel{Level: level.String(), Base: base.String()}
+	if !until.IsZero(){
+		resp.Until = &until
+	}
+	body, err := resp.MarshalJSON()
+	if err != nil{
+		logger(r).Warn(err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	if err != nil{
+		logger(r).Warn(err)
+	}
+}
+
+// HandleLogLevelGet answers with the current log level.
+func (a *Admin) HandleLogLevelGet(w http.ResponseWriter, r *http.Request){
+	a.writeLevel(w, r)
+}
+
+// HandleLogLevelRaise raises the log level, TRACE by default, for a while. Levels less verbose
+// than the configured one are rejected.
+func (a *Admin) HandleLogLevelRaise(w http.ResponseWriter, r *http.Request){
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger(r).Warn(err)
+		writeError(w, m.NewBadRequestError(err))
+		return
+	}
+	req := &m.LogLevelReq{}
+	if len(body) > 0{
+		err = req.UnmarshalJSON(body)
+		if err != nil{
+			logger(r).Warn(err)
+			writeError(w, m.NewBadRequestError(err))
+			return
+		}
+	}
+	level := log.TraceLevel
+	if req.Level != ""{
+		level, err = log.ParseLevel(req.Level)
+		if err != nil{
+			writeError(w, m.NewValidationError("level", "unknown log level " + req.Level))
+			return
+		}
+	}
+	// a raise can't silence logging below the configured level
+	if _, base, _ := a.levels.Level(); level < base{
+		writeError(w, m.NewValidationError("level", "log level " + level.String() + " is less verbose than " + base.String()))
+		return
+	}
+	d := a.raiseFor
+	if req.Seconds != 0{
+		d = time.Duration(req.Seconds) * time.Second
+	}
+	if d <= 0 || d > a.maxRaise{
+		writeError(w, m.NewValidationError("seconds", "seconds must be from 1 to " + strconv.Itoa(int(a.maxRaise / time.Second))))
+		return
+	}
+	until := a.levels.Raise(level, d)
+	logger(r).WithFields(log.Fields{"log_level": level.String(), "until": until}).Warn("log level raised")
+	a.writeLevel(w, r)
+}
+
+// HandleLogLevelReset brings the configured log level back.
+func (a *Admin) HandleLogLevelReset(w http.ResponseWriter, r *http.Request){
+	a.levels.Reset()
+	logger(r).Warn("log level reset")
+	a.writeLevel(w, r)
+}
+
+// NewAdmin raises log levels for raiseFor unless requests ask otherwise, never for longer than maxRaise.
+func NewAdmin(levels *logging.LevelSwitch, raiseFor time.Duration, maxRaise time.Duration) *Admin{
+	return &Admin{
+		levels: levels,
+		raiseFor: raiseFor,
+		maxRaise: maxRaise,
+	}
+}
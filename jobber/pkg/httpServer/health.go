@@ -0,0 +1,103 @@
+package httpServer
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+)
+
+// Pinger is a dependency the service can't work without.
+type Pinger interface{
+	Ping(ctx context.Context) error
+}
+
+// Health answers the liveness and readiness probes. Every dependency is pinged with timeout,
+// after Shutdown the service reports it isn't ready so load balancers drain its traffic.
+type Health struct{
+	timeout time.Duration
+	dependencies map[string]Pinger
+	shuttingDown int32
+}
+
+// Shutdown makes readiness fail from now on.
+func (h *Health) Shutdown(){
+	atomic.StoreInt32(&h.shuttingDown, 1)
+}
+
+// Readiness pings all dependencies at once.
+func (h *Health) Readiness(ctx context.Context) *m.Readiness{
+	resp := &m.Readiness{Status: m.StatusOK, Dependencies: map[string]m.Dependency{}}
+	if atomic.LoadInt32(&h.shuttingDown) == 1{
+		resp.Status = m.StatusShuttingDown
+		return resp
+	}
+	names := make([]string, 0, len(h.dependencies))
+	for name := range h.dependencies{
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names{
+		wg.Add(1)
+		go func(i int, dep Pinger){
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+			errs[i] = dep.Ping(pingCtx)
+		}(i, h.dependencies[name])
+	}
+	wg.Wait()
+	for i, name := range names{
+		if errs[i] != nil{
+			log.Warn(name + " is unavailable: ", errs[i])
+			resp.Status = m.StatusUnavailable
+			resp.Dependencies[name] = m.Dependency{Status: m.StatusUnavailable, Error: errs[i].Error()}
+			continue
+		}
+		resp.Dependencies[name] = m.Dependency{Status: m.StatusOK}
+	}
+	return resp
+}
+
+// HandleLiveness answers while the process is able to serve requests.
+func (h *Health) HandleLiveness(w http.ResponseWriter, r *http.Request){
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write([]byte(`{"status":"` + m.StatusOK + `"}`))
+	if err != nil{
+		log.Warn(err)
+	}
+}
+
+// HandleReadiness answers 503 Service Unavailable unless the service is ready.
+func (h *Health) HandleReadiness(w http.ResponseWriter, r *http.Request){
+	resp := h.Readiness(r.Context())
+	body, err := resp.MarshalJSON()
+	if err != nil{
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != m.StatusOK{
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, err = w.Write(body)
+	if err != nil{
+		log.Warn(err)
+	}
+}
+
+// NewHealth checks dependencies by their names, a ping fails after timeout.
+func NewHealth(timeout time.Duration, dependencies map[string]Pinger) *Health{
+	return &Health{
+		timeout: timeout,
+		dependencies: dependencies,
+	}
+}
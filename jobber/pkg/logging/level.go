@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LevelSwitch sets the level of the standard logger. A raised level lasts for a while
+// and then the configured one comes back by itself.
+type LevelSwitch struct{
+	mu sync.Mutex
+	base log.Level
+	until time.Time
+	timer *time.Timer
+	// raises tells the timer of the last raise from the timers of the earlier ones
+	raises int
+}
+
+// NewLevelSwitch sets the level of the standard logger to base.
+func NewLevelSwitch(base log.Level) *LevelSwitch{
+	log.SetLevel(base)
+	return &LevelSwitch{base: base}
+}
+
+// Raise sets level for d and returns the time base comes back. A raise replaces the one before it.
+func (s *LevelSwitch) Raise(level log.Level, d time.Duration) (until time.Time){
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil{
+		s.timer.Stop()
+	}
+	s.raises++
+	raise := s.raises
+	s.until = time.Now().Add(d)
+	s.timer = time.AfterFunc(d, func(){
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.raises == raise{
+			s.reset()
+		}
+	})
+	log.SetLevel(level)
+	return s.until
+}
+
+// Reset brings the configured level back before the raise ends.
+func (s *LevelSwitch) Reset(){
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+}
+
+func (s *LevelSwitch) reset(){
+	if s.timer != nil{
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.until = time.Time{}
+	log.SetLevel(s.base)
+}
+
+// Level returns the current and the configured levels. until is zero unless the level is raised.
+func (s *LevelSwitch) Level() (level log.Level, base log.Level, until time.Time){
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return log.GetLevel(), s.base, s.until
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type holder struct{
+	mu sync.Mutex
+	entry *log.Entry
+}
+
+type ctxKey struct{}
+
+// NewContext returns ctx carrying the logger of a request. Fields added to ctx
+// or to its children by AddFields are seen by everybody holding ctx.
+func NewContext(ctx context.Context, entry *log.Entry) context.Context{
+	return context.WithValue(ctx, ctxKey{}, &holder{entry: entry})
+}
+
+// FromContext returns the logger of the request of ctx, or the standard logger outside of requests.
+func FromContext(ctx context.Context) *log.Entry{
+	h, ok := ctx.Value(ctxKey{}).(*holder)
+	if !ok{
+		return log.NewEntry(log.StandardLogger())
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.entry
+}
+
+// AddFields adds fields to the logger of the request of ctx.
+func AddFields(ctx context.Context, fields map[string]interface{}){
+	h, ok := ctx.Value(ctxKey{}).(*holder)
+	if !ok{
+		return
+	}
+	h.mu.Lock()
+	h.entry = h.entry.WithFields(fields)
+	h.mu.Unlock()
+}
+
+// Redacted replaces the values of sensitive fields.
+const Redacted = "[REDACTED]"
+
+// sensitive are the parts of names of fields carrying credentials.
+var sensitive = []string{"password", "secret", "token", "authorization", "api_key", "apikey", "cookie"}
+
+func isSensitive(field string) bool{
+	field = strings.ToLower(field)
+	for _, s := range sensitive{
+		if strings.Contains(field, s){
+			return true
+		}
+	}
+	return false
+}
+
+type redactingFormatter struct{
+	log.Formatter
+}
+
+func (f redactingFormatter) Format(entry *log.Entry) ([]byte, error){
+	redact := false
+	for field := range entry.Data{
+		if isSensitive(field){
+			redact = true
+			break
+		}
+	}
+	if !redact{
+		return f.Formatter.Format(entry)
+	}
+	// the data of an entry is shared with the logger it was made by, so it is copied
+	data := make(log.Fields, len(entry.Data))
+	for field, value := range entry.Data{
+		if isSensitive(field){
+			value = Redacted
+		}
+		data[field] = value
+	}
+	redacted := *entry
+	redacted.Data = data
+	return f.Formatter.Format(&redacted)
+}
+
+// NewRedactingFormatter hides the values of fields like password, token or authorization from f.
+func NewRedactingFormatter(f log.Formatter) log.Formatter{
+	return redactingFormatter{Formatter: f}
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestContext(t *testing.T){
+	if entry := FromContext(context.Background()); entry.Logger != log.StandardLogger(){
+		t.Errorf("logger outside of requests isn't the standard one")
+	}
+	AddFields(context.Background(), log.Fields{"user_id": 1})
+
+	ctx := NewContext(context.Background(), log.WithField("request_id", "req-1"))
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	AddFields(child, log.Fields{"user_id": 1})
+	data := FromContext(ctx).Data
+	if data["request_id"] != "req-1" || data["user_id"] != 1{
+		t.Errorf("unexpected fields: %v", data)
+	}
+}
+
+func TestRedactingFormatter(t *testing.T){
+	cases := []struct{
+		Fields   log.Fields
+		Expected map[string]interface{}
+	}{
+		{
+			Fields: log.Fields{"user_id": 1, "amount": 400},
+			Expected: map[string]interface{}{"user_id": 1.0, "amount": 400.0},
+		},
+		{
+			Fields: log.Fields{"user_id": 1, "Authorization": "Bearer abc", "api_key": "k", "password": "p",
+				"refresh_token": "t", "client_secret": "s", "cookie": "c"},
+			Expected: map[string]interface{}{"user_id": 1.0, "Authorization": Redacted, "api_key": Redacted,
+				"password": Redacted, "refresh_token": Redacted, "client_secret": Redacted, "cookie": Redacted},
+		},
+	}
+	for num, c := range cases{
+		out := &bytes.Buffer{}
+		logger := log.New()
+		logger.SetOutput(out)
+		logger.SetFormatter(NewRedactingFormatter(&log.JSONFormatter{DisableTimestamp: true}))
+		entry := logger.WithFields(c.Fields)
+		entry.Info("test")
+		got := map[string]interface{}{}
+		err := json.Unmarshal(out.Bytes(), &got)
+		if err != nil{
+			t.Fatalf("[%d] %s", num, err)
+		}
+		for field, value := range c.Expected{
+			if got[field] != value{
+				t.Errorf("[%d] unexpected %s: %v, expected: %v", num, field, got[field], value)
+			}
+		}
+		for field, value := range c.Fields{
+			if entry.Data[field] != value{
+				t.Errorf("[%d] field %s of the entry was changed: %v", num, field, entry.Data[field])
+			}
+		}
+	}
+}
+
+func TestLevelSwitch(t *testing.T){
+	defer log.SetLevel(log.GetLevel())
+	levels := NewLevelSwitch(log.WarnLevel)
+	until := levels.Raise(log.TraceLevel, 20*time.Millisecond)
+	if level, base, u := levels.Level(); level != log.TraceLevel || base != log.WarnLevel || !u.Equal(until){
+		t.Errorf("unexpected raised level: %v, base %v, until %v", level, base, u)
+	}
+	// a new raise outlives the timer of the one before
+	levels.Raise(log.DebugLevel, time.Minute)
+	time.Sleep(50*time.Millisecond)
+	if log.GetLevel() != log.DebugLevel{
+		t.Errorf("level is reverted by an earlier raise: %v", log.GetLevel())
+	}
+	levels.Reset()
+	if level, _, u := levels.Level(); level != log.WarnLevel || !u.IsZero(){
+		t.Errorf("level isn't reset: %v, until %v", level, u)
+	}
+
+	levels.Raise(log.TraceLevel, 10*time.Millisecond)
+	time.Sleep(50*time.Millisecond)
+	if level, _, u := levels.Level(); level != log.WarnLevel || !u.IsZero(){
+		t.Errorf("level isn't reverted: %v, until %v", level, u)
+	}
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "jobber"
+
+// Results of rate lookups and upstream calls.
+const(
+	ResultHit = "hit"
+	ResultMiss = "miss"
+	ResultOK = "ok"
+	ResultError = "error"
+)
+
+var(
+	// Requests counts the handled requests by route name, method and status code.
+	Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name: "requests_total",
+		Help: "Handled HTTP requests.",
+	}, []string{"route", "method", "code"})
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name: "request_duration_seconds",
+		Help: "Time of handling HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	// RateLimited counts the requests denied by rate limits by route and the bucket which ran out.
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name: "rate_limited_total",
+		Help: "Requests denied by rate limits.",
+	}, []string{"route", "bucket"})
+
+	// SQLErrors counts errors returned by Postgres by their SQLSTATE code.
+	SQLErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name: "errors_total",
+		Help: "Errors returned by Postgres by SQLSTATE.",
+	}, []string{"code"})
+	Rollbacks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name: "rollbacks_total",
+		Help: "Rolled back transactions.",
+	})
+
+	// RateLookups counts the rates found in the cash (hit) and asked from the provider (miss).
+	RateLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rates",
+		Name: "cache_lookups_total",
+		Help: "Rate lookups in the Redis cache by result.",
+	}, []string{"result"})
+	RateProviderDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rates",
+		Name: "provider_request_duration_seconds",
+		Help: "Time of requests to the upstream rates service by result.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"result"})
+
+	// Credited and Debited sum the changes of user wallets in minor units by currency.
+	Credited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "credited_minor_units_total",
+		Help: "Money credited to user wallets in minor units.",
+	}, []string{"currency"})
+	Debited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "debited_minor_units_total",
+		Help: "Money debited from user wallets in minor units.",
+	}, []string{"currency"})
+	Transfers = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "transfers_total",
+		Help: "Transfers between users.",
+	})
+)
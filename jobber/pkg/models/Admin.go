@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// LogLevelReq raises the log level for Seconds. The level is TRACE and the time
+// is the configured one when they aren't set.
+type LogLevelReq struct {
+	Level			string				`json:"level"`
+	Seconds			int					`json:"seconds"`
+}
+
+// LogLevel is the current level of the log and the configured one, Until is set while a raised level lasts.
+type LogLevel struct {
+	Level			string				`json:"level"`
+	Base			string				`json:"base"`
+	Until			*time.Time			`json:"until,omitempty"`
+}
@@ -0,0 +1,183 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package models
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+	time "time"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson7893f62fDecodeGithubComFedorkolmykowAvitojobPkgModels(in *jlexer.Lexer, out *LogLevelReq) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "level":
+			out.Level = string(in.String())
+		case "seconds":
+			out.Seconds = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson7893f62fEncodeGithubComFedorkolmykowAvitojobPkgModels(out *jwriter.Writer, in LogLevelReq) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"level\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Level))
+	}
+	{
+		const prefix string = ",\"seconds\":"
+		out.RawString(prefix)
+		out.Int(int(in.Seconds))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v LogLevelReq) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson7893f62fEncodeGithubComFedorkolmykowAvitojobPkgModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v LogLevelReq) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson7893f62fEncodeGithubComFedorkolmykowAvitojobPkgModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *LogLevelReq) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson7893f62fDecodeGithubComFedorkolmykowAvitojobPkgModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *LogLevelReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson7893f62fDecodeGithubComFedorkolmykowAvitojobPkgModels(l, v)
+}
+func easyjson7893f62fDecodeGithubComFedorkolmykowAvitojobPkgModels1(in *jlexer.Lexer, out *LogLevel) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "level":
+			out.Level = string(in.String())
+		case "base":
+			out.Base = string(in.String())
+		case "until":
+			if in.IsNull() {
+				in.Skip()
+				out.Until = nil
+			} else {
+				if out.Until == nil {
+					out.Until = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.Until).UnmarshalJSON(data))
+				}
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson7893f62fEncodeGithubComFedorkolmykowAvitojobPkgModels1(out *jwriter.Writer, in LogLevel) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"level\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Level))
+	}
+	{
+		const prefix string = ",\"base\":"
+		out.RawString(prefix)
+		out.String(string(in.Base))
+	}
+	if in.Until != nil {
+		const prefix string = ",\"until\":"
+		out.RawString(prefix)
+		out.Raw((*in.Until).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v LogLevel) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson7893f62fEncodeGithubComFedorkolmykowAvitojobPkgModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v LogLevel) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson7893f62fEncodeGithubComFedorkolmykowAvitojobPkgModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *LogLevel) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson7893f62fDecodeGithubComFedorkolmykowAvitojobPkgModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *LogLevel) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson7893f62fDecodeGithubComFedorkolmykowAvitojobPkgModels1(l, v)
+}
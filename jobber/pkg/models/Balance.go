@@ -1,134 +1,317 @@
 package models
 
 import (
-	"errors"
 	"time"
 )
 
+const MaxIdempotencyKeyLen = 255
+
+// BaseCurrency is the currency of wallets and requests that don't name one.
+const BaseCurrency = "RUB"
+
+// ClientSystem is the client of transactions made by the service itself, like reconciliation corrections.
+const ClientSystem = "system"
+
 type ChangeBalanceReq struct {
 	UserId    int       `json:"user_id"`
-	Change    float64   `json:"change"`
+	Change    Money     `json:"change"`
+	Currency  string	`json:"currency"`
 	Comment   string	`json:"comment"`
 	Source    string    `json:"source"`
+	// Account is the system account on the other side of the change, one of ChangeAccounts.
+	// The default is the external account of Source.
+	Account   string    `json:"account,omitempty"`
+	IdempotencyKey string `json:"-"`
 }
 
 type ChangeBalanceResp struct {
 	UserId    int       `json:"user_id"`
-	Balance   float64   `json:"balance"`
+	Balance   Money     `json:"balance"`
+	Currency  string	`json:"currency"`
 }
 
+// TransferReq moves Change from the Currency wallet of the user to the TargetCurrency wallet
+// of the target. Rate and TargetChange are filled by the service when the currencies differ.
 type TransferReq struct {
 	UserId    int       `json:"user_id"`
-	Change    float64   `json:"change"`
+	Change    Money     `json:"change"`
+	Currency  string	`json:"currency"`
 	TargetId  int       `json:"target_id"`
+	TargetCurrency string `json:"target_currency"`
 	Comment   string	`json:"comment"`
+	Rate      float64   `json:"-"`
+	TargetChange Money  `json:"-"`
+	IdempotencyKey string `json:"-"`
 }
 
 type TransferResp struct {
+	TransferId int				`json:"transfer_id"`
+	Rate	   float64			`json:"rate"`
 	Source ChangeBalanceResp	`json:"source"`
 	Target ChangeBalanceResp	`json:"target"`
 }
 
+// GetBalanceReq asks for the Wallet balance of the user converted to Currency.
+// The balance is converted at the rate of RateDate, or at the current rate if RateDate is zero.
+// If AsOf is set, the balance is the one the user had at that instant, taken from the history.
 type GetBalanceReq struct {
 	UserId    int       `json:"user_id"`
+	Wallet    string	`json:"wallet"`
 	Currency  string	`json:"currency"`
+	RateDate  time.Time	`json:"rate_date"`
+	AsOf      time.Time	`json:"as_of"`
 }
 
+// GetBalanceResp of a balance as of an instant has no Held: holds aren't kept in the history.
 type GetBalanceResp struct {
 	UserId    int       `json:"user_id"`
-	Balance   float64   `json:"balance"`
+	Balance   Money     `json:"balance"`
+	Available Money     `json:"available"`
+	Held      Money     `json:"held"`
 	Currency  string	`json:"currency"`
+	AsOf      *time.Time `json:"as_of,omitempty"`
 }
 
+const MaxBalancesUsers = 1000
+
+// GetBalancesReq asks for the Wallet balances of many users as of an instant, the current instant
+// if AsOf is zero. Conversion is the same as in GetBalanceReq.
+type GetBalancesReq struct {
+	UserIds   []int     `json:"user_ids"`
+	Wallet    string	`json:"wallet"`
+	Currency  string	`json:"currency"`
+	RateDate  time.Time	`json:"rate_date"`
+	AsOf      time.Time	`json:"as_of"`
+}
+
+// GetBalancesResp lists the balances in the order of user ids. Users without the wallet are Missing.
+type GetBalancesResp struct {
+	AsOf      time.Time	`json:"as_of"`
+	Currency  string	`json:"currency"`
+	Balances  []GetBalanceResp `json:"balances"`
+	Missing   []int     `json:"missing"`
+}
+
+// RateDateLayout is the format of the dates of rate tables.
+const RateDateLayout = "2006-01-02"
+
 type Rate struct{
 	Rates     map[string]float64	`json:"rates"`
 	Base      string				`json:"base"`
 	Date      string				`json:"date"`
 }
 
+const(
+	DefaultTransactionsOnPage = 20
+	MaxTransactionsOnPage = 1000
+	// MaxPageOffset bounds the rows skipped by Page, which are read and discarded by the database.
+	// Further rows are reached by cursors.
+	MaxPageOffset = 10000
+)
+
+// GetTransactionsReq asks for a page of the user history. Pages follow each other by Cursor, Page is
+// deprecated: it skips (Page - 1) * TransactionsOnPage rows, at most MaxPageOffset, and a page past
+// the end of the history is empty. If Currency is set, every transaction also gets its change
+// converted to Currency at the rate of the day it was made.
 type GetTransactionsReq struct {
 	UserId    			int         `json:"user_id"`
 	Page				int			`json:"page"`
 	TransactionsOnPage 	int		    `json:"per_page"`
 	ChangeSort			bool		`json:"change_sort"`
 	TimeSort			bool		`json:"time_sort"`
+	Cursor				string		`json:"cursor"`
+	From				time.Time	`json:"from"`
+	To					time.Time	`json:"to"`
+	Currency			string		`json:"currency"`
+	After				*TransactionsCursor `json:"-"`
+}
+
+// TransactionsCursor is the position of the last transaction of the previous page.
+type TransactionsCursor struct{
+	Change				Money
+	Time				time.Time
+	TransId				int
 }
 
 type Transaction struct {
-	TransId			int					`json:"-" db:"trans_id"`
+	TransId			int					`json:"trans_id" db:"trans_id"`
 	UserId          int                 `json:"-" db:"user_id"`
-	InitialBalance  float64				`json:"init_balance" db:"init_balance"`
-	Change   		float64				`json:"change" db:"change"`
-	ChangeTime 		string				`json:"change_time" db:"time"`
+	InitialBalance  Money				`json:"init_balance" db:"init_balance"`
+	Change   		Money				`json:"change" db:"change"`
+	Currency   		string				`json:"currency" db:"currency"`
+	ChangeTime 		time.Time			`json:"change_time" db:"time"`
 	Source          string              `json:"source" db:"source"`
 	Comment     	string				`json:"comment" db:"comment"`
+	RefundOf		*int				`json:"refund_of,omitempty" db:"refund_of"`
+	TransferId		*int				`json:"transfer_id,omitempty" db:"transfer_id"`
+	Rate			*float64			`json:"rate,omitempty" db:"rate"`
+	CounterChange	*Money				`json:"counter_change,omitempty" db:"counter_change"`
+	CounterCurrency	*string				`json:"counter_currency,omitempty" db:"counter_currency"`
+	// Corrects is the transaction before which a corrective transaction fills a gap in the history.
+	Corrects		*int				`json:"corrects,omitempty" db:"corrects"`
+	// ClientId is the authenticated client which moved the money, ClientSystem for the service itself.
+	ClientId		string				`json:"client_id,omitempty" db:"client_id"`
+	Converted		*Money				`json:"converted,omitempty" db:"-"`
 }
 
 type Transactions struct{
 	Transactions		[]Transaction
-	ChangeSort			bool
-	TimeSort			bool
+	HasMore				bool
 }
 
 type GetTransactionsResp struct {
 	UserId    			int         	`json:"user_id"`
 	Transactions		[]Transaction   `json:"transactions"`
+	NextCursor			string			`json:"next_cursor,omitempty"`
 }
 
-
-func (t Transactions) Less(i, j int) bool {
-	if t.ChangeSort{
-		return t.Transactions[i].Change < t.Transactions[j].Change
+// ValidateCurrency checks that currency in the field is a three letter ISO 4217 code.
+func ValidateCurrency(field string, currency string) error{
+	if len(currency) != 3 {
+		return NewValidationError(field, "invalid currency " + currency)
 	}
-	if t.TimeSort{
-		ti, _ := time.Parse(time.RFC822 ,t.Transactions[i].ChangeTime)
-		tj, _ := time.Parse(time.RFC822 ,t.Transactions[j].ChangeTime)
-		return ti.Before(tj)
+	for _, c := range currency {
+		if c < 'A' || c > 'Z' {
+			return NewValidationError(field, "invalid currency " + currency)
+		}
 	}
-	return false
-}
-
-func (t Transactions) Swap(i, j int) {
-	t.Transactions[i], t.Transactions[j] = t.Transactions[j], t.Transactions[i]
+	return nil
 }
 
-func (t Transactions) Len() int {
-	return len(t.Transactions)
+func validateAmount(field string, amount Money, currency string) error{
+	err := ValidateCurrency("currency", currency)
+	if err != nil {
+		return err
+	}
+	if !amount.FitsCurrency(currency) {
+		return NewValidationError(field, "amount is too precise for " + currency)
+	}
+	return nil
 }
 
 func (c *ChangeBalanceReq) Validate() error{
 	if c.UserId < 0 {
-		return errors.New("user id can't be negative")
+		return NewValidationError("user_id", "user id can't be negative")
+	}
+	if err := validateAmount("change", c.Change, c.Currency); err != nil {
+		return err
+	}
+	if c.Account != "" && !changeAccount(c.Account) {
+		return NewValidationError("account", "unknown account " + c.Account)
+	}
+	if len(c.IdempotencyKey) > MaxIdempotencyKeyLen {
+		return NewValidationError("idempotency_key", "idempotency key is too long")
 	}
 	return nil
 }
 
+func changeAccount(account string) bool{
+	for _, a := range ChangeAccounts {
+		if a == account {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *TransferReq) Validate() error{
 	if t.Change < 0{
-		return errors.New("transfer change cannot be negative")
+		return NewValidationError("change", "transfer change cannot be negative")
 	}
 	if t.UserId < 0 {
-		return errors.New("user id can't be negative")
+		return NewValidationError("user_id", "user id can't be negative")
+	}
+	if err := validateAmount("change", t.Change, t.Currency); err != nil {
+		return err
+	}
+	if err := ValidateCurrency("target_currency", t.TargetCurrency); err != nil {
+		return err
+	}
+	if t.UserId == t.TargetId && t.Currency == t.TargetCurrency {
+		return NewValidationError("target_id", "transfer to the same wallet")
+	}
+	if len(t.IdempotencyKey) > MaxIdempotencyKeyLen {
+		return NewValidationError("idempotency_key", "idempotency key is too long")
 	}
 	return nil
 }
 
 func (g *GetBalanceReq) Validate() error{
 	if g.UserId < 0 {
-		return errors.New("user id can't be negative")
+		return NewValidationError("user_id", "user id can't be negative")
+	}
+	if err := ValidateCurrency("wallet", g.Wallet); err != nil {
+		return err
+	}
+	if err := ValidateCurrency("currency", g.Currency); err != nil {
+		return err
+	}
+	if g.RateDate.After(time.Now()) {
+		return NewValidationError("rate_date", "rate date is in the future")
+	}
+	if g.AsOf.After(time.Now()) {
+		return NewValidationError("as_of", "as of is in the future")
+	}
+	return nil
+}
+
+func (g *GetBalancesReq) Validate() error{
+	if len(g.UserIds) == 0 {
+		return NewValidationError("user_ids", "no user ids")
+	}
+	if len(g.UserIds) > MaxBalancesUsers {
+		return NewValidationError("user_ids", "too many user ids")
+	}
+	for _, id := range g.UserIds {
+		if id < 0 {
+			return NewValidationError("user_ids", "user id can't be negative")
+		}
+	}
+	if err := ValidateCurrency("wallet", g.Wallet); err != nil {
+		return err
+	}
+	if err := ValidateCurrency("currency", g.Currency); err != nil {
+		return err
+	}
+	if g.RateDate.After(time.Now()) {
+		return NewValidationError("rate_date", "rate date is in the future")
+	}
+	if g.AsOf.After(time.Now()) {
+		return NewValidationError("as_of", "as of is in the future")
 	}
 	return nil
 }
 
 func (g *GetTransactionsReq) Validate() error {
 	if g.Page < 0 {
-		return errors.New("negative page")
+		return NewValidationError("page", "negative page")
 	}
 	if g.TransactionsOnPage < 0 {
-		return errors.New("negative number of transactions on page")
+		return NewValidationError("per_page", "negative number of transactions on page")
+	}
+	if g.TransactionsOnPage > MaxTransactionsOnPage {
+		return NewValidationError("per_page", "too many transactions on page")
+	}
+	if g.Cursor != "" && g.Page > 1 {
+		return NewValidationError("cursor", "page and cursor can't be used together")
+	}
+	perPage := g.TransactionsOnPage
+	if perPage == 0 {
+		perPage = DefaultTransactionsOnPage
+	}
+	if g.Page - 1 > MaxPageOffset / perPage {
+		return NewValidationError("page", "page is too far, use cursor")
+	}
+	if !g.From.IsZero() && !g.To.IsZero() && g.To.Before(g.From) {
+		return NewValidationError("to", "end of period is before its start")
+	}
+	if g.Currency != "" {
+		if err := ValidateCurrency("currency", g.Currency); err != nil {
+			return err
+		}
 	}
 	if g.UserId < 0 {
-		return errors.New("user id can't be negative")
+		return NewValidationError("user_id", "user id can't be negative")
 	}
 	return nil
 }
\ No newline at end of file
@@ -7,6 +7,7 @@ import (
 	easyjson "github.com/mailru/easyjson"
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
+	time "time"
 )
 
 // suppress unused package warning
@@ -36,6 +37,10 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels(in *jlexer.Le
 			continue
 		}
 		switch key {
+		case "transfer_id":
+			out.TransferId = int(in.Int())
+		case "rate":
+			out.Rate = float64(in.Float64())
 		case "source":
 			(out.Source).UnmarshalEasyJSON(in)
 		case "target":
@@ -55,8 +60,18 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels(out *jwriter.
 	first := true
 	_ = first
 	{
-		const prefix string = ",\"source\":"
+		const prefix string = ",\"transfer_id\":"
 		out.RawString(prefix[1:])
+		out.Int(int(in.TransferId))
+	}
+	{
+		const prefix string = ",\"rate\":"
+		out.RawString(prefix)
+		out.Float64(float64(in.Rate))
+	}
+	{
+		const prefix string = ",\"source\":"
+		out.RawString(prefix)
 		(in.Source).MarshalEasyJSON(out)
 	}
 	{
@@ -112,9 +127,13 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels1(in *jlexer.L
 		case "user_id":
 			out.UserId = int(in.Int())
 		case "change":
-			out.Change = float64(in.Float64())
+			(out.Change).UnmarshalEasyJSON(in)
+		case "currency":
+			out.Currency = string(in.String())
 		case "target_id":
 			out.TargetId = int(in.Int())
+		case "target_currency":
+			out.TargetCurrency = string(in.String())
 		case "comment":
 			out.Comment = string(in.String())
 		default:
@@ -139,13 +158,23 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels1(out *jwriter
 	{
 		const prefix string = ",\"change\":"
 		out.RawString(prefix)
-		out.Float64(float64(in.Change))
+		(in.Change).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
 	}
 	{
 		const prefix string = ",\"target_id\":"
 		out.RawString(prefix)
 		out.Int(int(in.TargetId))
 	}
+	{
+		const prefix string = ",\"target_currency\":"
+		out.RawString(prefix)
+		out.String(string(in.TargetCurrency))
+	}
 	{
 		const prefix string = ",\"comment\":"
 		out.RawString(prefix)
@@ -177,7 +206,89 @@ func (v *TransferReq) UnmarshalJSON(data []byte) error {
 func (v *TransferReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels1(l, v)
 }
-func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels2(in *jlexer.Lexer, out *Transactions) {
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels2(in *jlexer.Lexer, out *TransactionsCursor) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "Change":
+			(out.Change).UnmarshalEasyJSON(in)
+		case "Time":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Time).UnmarshalJSON(data))
+			}
+		case "TransId":
+			out.TransId = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels2(out *jwriter.Writer, in TransactionsCursor) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"Change\":"
+		out.RawString(prefix[1:])
+		(in.Change).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"Time\":"
+		out.RawString(prefix)
+		out.Raw((in.Time).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"TransId\":"
+		out.RawString(prefix)
+		out.Int(int(in.TransId))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v TransactionsCursor) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v TransactionsCursor) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *TransactionsCursor) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *TransactionsCursor) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels2(l, v)
+}
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels3(in *jlexer.Lexer, out *Transactions) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -219,10 +330,8 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels2(in *jlexer.L
 				}
 				in.Delim(']')
 			}
-		case "ChangeSort":
-			out.ChangeSort = bool(in.Bool())
-		case "TimeSort":
-			out.TimeSort = bool(in.Bool())
+		case "HasMore":
+			out.HasMore = bool(in.Bool())
 		default:
 			in.SkipRecursive()
 		}
@@ -233,7 +342,7 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels2(in *jlexer.L
 		in.Consumed()
 	}
 }
-func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels2(out *jwriter.Writer, in Transactions) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels3(out *jwriter.Writer, in Transactions) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -254,14 +363,9 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels2(out *jwriter
 		}
 	}
 	{
-		const prefix string = ",\"ChangeSort\":"
+		const prefix string = ",\"HasMore\":"
 		out.RawString(prefix)
-		out.Bool(bool(in.ChangeSort))
-	}
-	{
-		const prefix string = ",\"TimeSort\":"
-		out.RawString(prefix)
-		out.Bool(bool(in.TimeSort))
+		out.Bool(bool(in.HasMore))
 	}
 	out.RawByte('}')
 }
@@ -269,27 +373,27 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels2(out *jwriter
 // MarshalJSON supports json.Marshaler interface
 func (v Transactions) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels2(&w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels3(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Transactions) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels2(w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels3(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Transactions) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels2(&r, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels3(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Transactions) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels2(l, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels3(l, v)
 }
-func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels3(in *jlexer.Lexer, out *Transaction) {
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels4(in *jlexer.Lexer, out *Transaction) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -308,16 +412,94 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels3(in *jlexer.L
 			continue
 		}
 		switch key {
+		case "trans_id":
+			out.TransId = int(in.Int())
 		case "init_balance":
-			out.InitialBalance = float64(in.Float64())
+			(out.InitialBalance).UnmarshalEasyJSON(in)
 		case "change":
-			out.Change = float64(in.Float64())
+			(out.Change).UnmarshalEasyJSON(in)
+		case "currency":
+			out.Currency = string(in.String())
 		case "change_time":
-			out.ChangeTime = string(in.String())
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.ChangeTime).UnmarshalJSON(data))
+			}
 		case "source":
 			out.Source = string(in.String())
 		case "comment":
 			out.Comment = string(in.String())
+		case "refund_of":
+			if in.IsNull() {
+				in.Skip()
+				out.RefundOf = nil
+			} else {
+				if out.RefundOf == nil {
+					out.RefundOf = new(int)
+				}
+				*out.RefundOf = int(in.Int())
+			}
+		case "transfer_id":
+			if in.IsNull() {
+				in.Skip()
+				out.TransferId = nil
+			} else {
+				if out.TransferId == nil {
+					out.TransferId = new(int)
+				}
+				*out.TransferId = int(in.Int())
+			}
+		case "rate":
+			if in.IsNull() {
+				in.Skip()
+				out.Rate = nil
+			} else {
+				if out.Rate == nil {
+					out.Rate = new(float64)
+				}
+				*out.Rate = float64(in.Float64())
+			}
+		case "counter_change":
+			if in.IsNull() {
+				in.Skip()
+				out.CounterChange = nil
+			} else {
+				if out.CounterChange == nil {
+					out.CounterChange = new(Money)
+				}
+				(*out.CounterChange).UnmarshalEasyJSON(in)
+			}
+		case "counter_currency":
+			if in.IsNull() {
+				in.Skip()
+				out.CounterCurrency = nil
+			} else {
+				if out.CounterCurrency == nil {
+					out.CounterCurrency = new(string)
+				}
+				*out.CounterCurrency = string(in.String())
+			}
+		case "corrects":
+			if in.IsNull() {
+				in.Skip()
+				out.Corrects = nil
+			} else {
+				if out.Corrects == nil {
+					out.Corrects = new(int)
+				}
+				*out.Corrects = int(in.Int())
+			}
+		case "client_id":
+			out.ClientId = string(in.String())
+		case "converted":
+			if in.IsNull() {
+				in.Skip()
+				out.Converted = nil
+			} else {
+				if out.Converted == nil {
+					out.Converted = new(Money)
+				}
+				(*out.Converted).UnmarshalEasyJSON(in)
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -328,29 +510,34 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels3(in *jlexer.L
 		in.Consumed()
 	}
 }
-func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels3(out *jwriter.Writer, in Transaction) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels4(out *jwriter.Writer, in Transaction) {
 	out.RawByte('{')
 	first := true
 	_ = first
+	{
+		const prefix string = ",\"trans_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.TransId))
+	}
 	{
 		const prefix string = ",\"init_balance\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
-		out.Float64(float64(in.InitialBalance))
+		out.RawString(prefix)
+		(in.InitialBalance).MarshalEasyJSON(out)
 	}
 	{
 		const prefix string = ",\"change\":"
 		out.RawString(prefix)
-		out.Float64(float64(in.Change))
+		(in.Change).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
 	}
 	{
 		const prefix string = ",\"change_time\":"
 		out.RawString(prefix)
-		out.String(string(in.ChangeTime))
+		out.Raw((in.ChangeTime).MarshalJSON())
 	}
 	{
 		const prefix string = ",\"source\":"
@@ -362,33 +549,73 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels3(out *jwriter
 		out.RawString(prefix)
 		out.String(string(in.Comment))
 	}
+	if in.RefundOf != nil {
+		const prefix string = ",\"refund_of\":"
+		out.RawString(prefix)
+		out.Int(int(*in.RefundOf))
+	}
+	if in.TransferId != nil {
+		const prefix string = ",\"transfer_id\":"
+		out.RawString(prefix)
+		out.Int(int(*in.TransferId))
+	}
+	if in.Rate != nil {
+		const prefix string = ",\"rate\":"
+		out.RawString(prefix)
+		out.Float64(float64(*in.Rate))
+	}
+	if in.CounterChange != nil {
+		const prefix string = ",\"counter_change\":"
+		out.RawString(prefix)
+		(*in.CounterChange).MarshalEasyJSON(out)
+	}
+	if in.CounterCurrency != nil {
+		const prefix string = ",\"counter_currency\":"
+		out.RawString(prefix)
+		out.String(string(*in.CounterCurrency))
+	}
+	if in.Corrects != nil {
+		const prefix string = ",\"corrects\":"
+		out.RawString(prefix)
+		out.Int(int(*in.Corrects))
+	}
+	if in.ClientId != "" {
+		const prefix string = ",\"client_id\":"
+		out.RawString(prefix)
+		out.String(string(in.ClientId))
+	}
+	if in.Converted != nil {
+		const prefix string = ",\"converted\":"
+		out.RawString(prefix)
+		(*in.Converted).MarshalEasyJSON(out)
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v Transaction) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels3(&w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels4(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Transaction) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels3(w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels4(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Transaction) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels3(&r, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels4(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Transaction) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels3(l, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels4(l, v)
 }
-func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels4(in *jlexer.Lexer, out *Rate) {
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels5(in *jlexer.Lexer, out *Rate) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -437,7 +664,7 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels4(in *jlexer.L
 		in.Consumed()
 	}
 }
-func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels4(out *jwriter.Writer, in Rate) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels5(out *jwriter.Writer, in Rate) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -478,27 +705,27 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels4(out *jwriter
 // MarshalJSON supports json.Marshaler interface
 func (v Rate) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels4(&w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels5(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Rate) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels4(w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels5(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Rate) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels4(&r, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels5(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Rate) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels4(l, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels5(l, v)
 }
-func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels5(in *jlexer.Lexer, out *GetTransactionsResp) {
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels6(in *jlexer.Lexer, out *GetTransactionsResp) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -542,6 +769,8 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels5(in *jlexer.L
 				}
 				in.Delim(']')
 			}
+		case "next_cursor":
+			out.NextCursor = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -552,7 +781,7 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels5(in *jlexer.L
 		in.Consumed()
 	}
 }
-func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels5(out *jwriter.Writer, in GetTransactionsResp) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels6(out *jwriter.Writer, in GetTransactionsResp) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -577,33 +806,38 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels5(out *jwriter
 			out.RawByte(']')
 		}
 	}
+	if in.NextCursor != "" {
+		const prefix string = ",\"next_cursor\":"
+		out.RawString(prefix)
+		out.String(string(in.NextCursor))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v GetTransactionsResp) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels5(&w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels6(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v GetTransactionsResp) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels5(w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels6(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *GetTransactionsResp) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels5(&r, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels6(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *GetTransactionsResp) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels5(l, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels6(l, v)
 }
-func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels6(in *jlexer.Lexer, out *GetTransactionsReq) {
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels7(in *jlexer.Lexer, out *GetTransactionsReq) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -632,6 +866,18 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels6(in *jlexer.L
 			out.ChangeSort = bool(in.Bool())
 		case "time_sort":
 			out.TimeSort = bool(in.Bool())
+		case "cursor":
+			out.Cursor = string(in.String())
+		case "from":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.From).UnmarshalJSON(data))
+			}
+		case "to":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.To).UnmarshalJSON(data))
+			}
+		case "currency":
+			out.Currency = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -642,7 +888,7 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels6(in *jlexer.L
 		in.Consumed()
 	}
 }
-func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels6(out *jwriter.Writer, in GetTransactionsReq) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels7(out *jwriter.Writer, in GetTransactionsReq) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -671,33 +917,336 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels6(out *jwriter
 		out.RawString(prefix)
 		out.Bool(bool(in.TimeSort))
 	}
+	{
+		const prefix string = ",\"cursor\":"
+		out.RawString(prefix)
+		out.String(string(in.Cursor))
+	}
+	{
+		const prefix string = ",\"from\":"
+		out.RawString(prefix)
+		out.Raw((in.From).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"to\":"
+		out.RawString(prefix)
+		out.Raw((in.To).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v GetTransactionsReq) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels6(&w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels7(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v GetTransactionsReq) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels6(w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels7(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *GetTransactionsReq) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels6(&r, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels7(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *GetTransactionsReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels6(l, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels7(l, v)
+}
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels8(in *jlexer.Lexer, out *GetBalancesResp) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "as_of":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.AsOf).UnmarshalJSON(data))
+			}
+		case "currency":
+			out.Currency = string(in.String())
+		case "balances":
+			if in.IsNull() {
+				in.Skip()
+				out.Balances = nil
+			} else {
+				in.Delim('[')
+				if out.Balances == nil {
+					if !in.IsDelim(']') {
+						out.Balances = make([]GetBalanceResp, 0, 1)
+					} else {
+						out.Balances = []GetBalanceResp{}
+					}
+				} else {
+					out.Balances = (out.Balances)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v9 GetBalanceResp
+					(v9).UnmarshalEasyJSON(in)
+					out.Balances = append(out.Balances, v9)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "missing":
+			if in.IsNull() {
+				in.Skip()
+				out.Missing = nil
+			} else {
+				in.Delim('[')
+				if out.Missing == nil {
+					if !in.IsDelim(']') {
+						out.Missing = make([]int, 0, 8)
+					} else {
+						out.Missing = []int{}
+					}
+				} else {
+					out.Missing = (out.Missing)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v10 int
+					v10 = int(in.Int())
+					out.Missing = append(out.Missing, v10)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels8(out *jwriter.Writer, in GetBalancesResp) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"as_of\":"
+		out.RawString(prefix[1:])
+		out.Raw((in.AsOf).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"balances\":"
+		out.RawString(prefix)
+		if in.Balances == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v11, v12 := range in.Balances {
+				if v11 > 0 {
+					out.RawByte(',')
+				}
+				(v12).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"missing\":"
+		out.RawString(prefix)
+		if in.Missing == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v13, v14 := range in.Missing {
+				if v13 > 0 {
+					out.RawByte(',')
+				}
+				out.Int(int(v14))
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GetBalancesResp) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels8(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GetBalancesResp) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels8(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GetBalancesResp) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels8(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GetBalancesResp) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels8(l, v)
+}
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels9(in *jlexer.Lexer, out *GetBalancesReq) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "user_ids":
+			if in.IsNull() {
+				in.Skip()
+				out.UserIds = nil
+			} else {
+				in.Delim('[')
+				if out.UserIds == nil {
+					if !in.IsDelim(']') {
+						out.UserIds = make([]int, 0, 8)
+					} else {
+						out.UserIds = []int{}
+					}
+				} else {
+					out.UserIds = (out.UserIds)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v15 int
+					v15 = int(in.Int())
+					out.UserIds = append(out.UserIds, v15)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "wallet":
+			out.Wallet = string(in.String())
+		case "currency":
+			out.Currency = string(in.String())
+		case "rate_date":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.RateDate).UnmarshalJSON(data))
+			}
+		case "as_of":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.AsOf).UnmarshalJSON(data))
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
 }
-func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels7(in *jlexer.Lexer, out *GetBalanceResp) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels9(out *jwriter.Writer, in GetBalancesReq) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"user_ids\":"
+		out.RawString(prefix[1:])
+		if in.UserIds == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v16, v17 := range in.UserIds {
+				if v16 > 0 {
+					out.RawByte(',')
+				}
+				out.Int(int(v17))
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"wallet\":"
+		out.RawString(prefix)
+		out.String(string(in.Wallet))
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"rate_date\":"
+		out.RawString(prefix)
+		out.Raw((in.RateDate).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"as_of\":"
+		out.RawString(prefix)
+		out.Raw((in.AsOf).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GetBalancesReq) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels9(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GetBalancesReq) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels9(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GetBalancesReq) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels9(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GetBalancesReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels9(l, v)
+}
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels10(in *jlexer.Lexer, out *GetBalanceResp) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -719,9 +1268,25 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels7(in *jlexer.L
 		case "user_id":
 			out.UserId = int(in.Int())
 		case "balance":
-			out.Balance = float64(in.Float64())
+			(out.Balance).UnmarshalEasyJSON(in)
+		case "available":
+			(out.Available).UnmarshalEasyJSON(in)
+		case "held":
+			(out.Held).UnmarshalEasyJSON(in)
 		case "currency":
 			out.Currency = string(in.String())
+		case "as_of":
+			if in.IsNull() {
+				in.Skip()
+				out.AsOf = nil
+			} else {
+				if out.AsOf == nil {
+					out.AsOf = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.AsOf).UnmarshalJSON(data))
+				}
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -732,7 +1297,7 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels7(in *jlexer.L
 		in.Consumed()
 	}
 }
-func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels7(out *jwriter.Writer, in GetBalanceResp) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels10(out *jwriter.Writer, in GetBalanceResp) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -744,40 +1309,55 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels7(out *jwriter
 	{
 		const prefix string = ",\"balance\":"
 		out.RawString(prefix)
-		out.Float64(float64(in.Balance))
+		(in.Balance).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"available\":"
+		out.RawString(prefix)
+		(in.Available).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"held\":"
+		out.RawString(prefix)
+		(in.Held).MarshalEasyJSON(out)
 	}
 	{
 		const prefix string = ",\"currency\":"
 		out.RawString(prefix)
 		out.String(string(in.Currency))
 	}
+	if in.AsOf != nil {
+		const prefix string = ",\"as_of\":"
+		out.RawString(prefix)
+		out.Raw((*in.AsOf).MarshalJSON())
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v GetBalanceResp) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels7(&w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels10(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v GetBalanceResp) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels7(w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels10(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *GetBalanceResp) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels7(&r, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels10(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *GetBalanceResp) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels7(l, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels10(l, v)
 }
-func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels8(in *jlexer.Lexer, out *GetBalanceReq) {
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels11(in *jlexer.Lexer, out *GetBalanceReq) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -798,8 +1378,18 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels8(in *jlexer.L
 		switch key {
 		case "user_id":
 			out.UserId = int(in.Int())
+		case "wallet":
+			out.Wallet = string(in.String())
 		case "currency":
 			out.Currency = string(in.String())
+		case "rate_date":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.RateDate).UnmarshalJSON(data))
+			}
+		case "as_of":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.AsOf).UnmarshalJSON(data))
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -810,7 +1400,7 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels8(in *jlexer.L
 		in.Consumed()
 	}
 }
-func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels8(out *jwriter.Writer, in GetBalanceReq) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels11(out *jwriter.Writer, in GetBalanceReq) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -819,38 +1409,53 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels8(out *jwriter
 		out.RawString(prefix[1:])
 		out.Int(int(in.UserId))
 	}
+	{
+		const prefix string = ",\"wallet\":"
+		out.RawString(prefix)
+		out.String(string(in.Wallet))
+	}
 	{
 		const prefix string = ",\"currency\":"
 		out.RawString(prefix)
 		out.String(string(in.Currency))
 	}
+	{
+		const prefix string = ",\"rate_date\":"
+		out.RawString(prefix)
+		out.Raw((in.RateDate).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"as_of\":"
+		out.RawString(prefix)
+		out.Raw((in.AsOf).MarshalJSON())
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v GetBalanceReq) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels8(&w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels11(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v GetBalanceReq) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels8(w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels11(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *GetBalanceReq) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels8(&r, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels11(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *GetBalanceReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels8(l, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels11(l, v)
 }
-func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels9(in *jlexer.Lexer, out *ChangeBalanceResp) {
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels12(in *jlexer.Lexer, out *ChangeBalanceResp) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -872,7 +1477,9 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels9(in *jlexer.L
 		case "user_id":
 			out.UserId = int(in.Int())
 		case "balance":
-			out.Balance = float64(in.Float64())
+			(out.Balance).UnmarshalEasyJSON(in)
+		case "currency":
+			out.Currency = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -883,7 +1490,7 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels9(in *jlexer.L
 		in.Consumed()
 	}
 }
-func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels9(out *jwriter.Writer, in ChangeBalanceResp) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels12(out *jwriter.Writer, in ChangeBalanceResp) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -895,7 +1502,12 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels9(out *jwriter
 	{
 		const prefix string = ",\"balance\":"
 		out.RawString(prefix)
-		out.Float64(float64(in.Balance))
+		(in.Balance).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
 	}
 	out.RawByte('}')
 }
@@ -903,27 +1515,27 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels9(out *jwriter
 // MarshalJSON supports json.Marshaler interface
 func (v ChangeBalanceResp) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels9(&w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels12(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v ChangeBalanceResp) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels9(w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels12(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *ChangeBalanceResp) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels9(&r, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels12(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *ChangeBalanceResp) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels9(l, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels12(l, v)
 }
-func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels10(in *jlexer.Lexer, out *ChangeBalanceReq) {
+func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels13(in *jlexer.Lexer, out *ChangeBalanceReq) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -945,11 +1557,15 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels10(in *jlexer.
 		case "user_id":
 			out.UserId = int(in.Int())
 		case "change":
-			out.Change = float64(in.Float64())
+			(out.Change).UnmarshalEasyJSON(in)
+		case "currency":
+			out.Currency = string(in.String())
 		case "comment":
 			out.Comment = string(in.String())
 		case "source":
 			out.Source = string(in.String())
+		case "account":
+			out.Account = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -960,7 +1576,7 @@ func easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels10(in *jlexer.
 		in.Consumed()
 	}
 }
-func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels10(out *jwriter.Writer, in ChangeBalanceReq) {
+func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels13(out *jwriter.Writer, in ChangeBalanceReq) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -972,7 +1588,12 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels10(out *jwrite
 	{
 		const prefix string = ",\"change\":"
 		out.RawString(prefix)
-		out.Float64(float64(in.Change))
+		(in.Change).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
 	}
 	{
 		const prefix string = ",\"comment\":"
@@ -984,29 +1605,34 @@ func easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels10(out *jwrite
 		out.RawString(prefix)
 		out.String(string(in.Source))
 	}
+	if in.Account != "" {
+		const prefix string = ",\"account\":"
+		out.RawString(prefix)
+		out.String(string(in.Account))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v ChangeBalanceReq) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels10(&w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels13(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v ChangeBalanceReq) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels10(w, v)
+	easyjson45d5b42eEncodeGithubComFedorkolmykowAvitojobPkgModels13(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *ChangeBalanceReq) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels10(&r, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels13(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *ChangeBalanceReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels10(l, v)
+	easyjson45d5b42eDecodeGithubComFedorkolmykowAvitojobPkgModels13(l, v)
 }
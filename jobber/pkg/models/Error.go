@@ -0,0 +1,138 @@
+package models
+
+import "errors"
+
+// ErrorKind tells how a client should treat an error. httpServer maps every kind to a status code.
+type ErrorKind int
+
+const(
+	KindInternal ErrorKind = iota
+	KindBadRequest
+	KindValidation
+	KindNotFound
+	KindInsufficientFunds
+	KindConflict
+	KindUnavailable
+	KindUnauthorized
+	KindForbidden
+	KindRateLimited
+)
+
+// Error codes are stable, clients can rely on them.
+const(
+	CodeInternal = "internal_error"
+	CodeBadRequest = "bad_request"
+	CodeValidation = "validation_failed"
+	CodeUnsupportedCurrency = "unsupported_currency"
+	CodeWalletNotFound = "wallet_not_found"
+	CodeHoldNotFound = "hold_not_found"
+	CodeTransactionNotFound = "transaction_not_found"
+	CodeTransferNotFound = "transfer_not_found"
+	CodeRateNotFound = "rate_not_found"
+	CodeInsufficientFunds = "insufficient_funds"
+	CodeIdempotencyConflict = "idempotency_conflict"
+	CodeHoldSettled = "hold_settled"
+	CodeRefundNotAllowed = "refund_not_allowed"
+	CodeRefundExceeded = "refund_exceeded"
+	CodeConcurrentUpdate = "concurrent_update"
+	CodeDatabaseUnavailable = "database_unavailable"
+	CodeRatesUnavailable = "rates_unavailable"
+	CodeTimeout = "timeout"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden = "forbidden"
+	CodeRateLimited = "rate_limited"
+	CodeRateLimiterUnavailable = "rate_limiter_unavailable"
+)
+
+// Error is a domain error. It is also the JSON body of error responses.
+type Error struct{
+	Kind		ErrorKind		`json:"-"`
+	Code		string			`json:"code"`
+	Message		string			`json:"message"`
+	Fields		[]FieldError	`json:"fields,omitempty"`
+	Err			error			`json:"-"`
+}
+
+// FieldError describes what is wrong with one field of a request.
+type FieldError struct{
+	Field		string			`json:"field"`
+	Message		string			`json:"message"`
+}
+
+func (e *Error) Error() string{
+	if e.Err != nil{
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error{
+	return e.Err
+}
+
+// Is matches errors by code, so errors.Is(err, ErrInsufficientFunds) holds for any error with its code.
+func (e *Error) Is(target error) bool{
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// KindOf returns the kind of the first Error in the chain of err, KindInternal if there is none.
+func KindOf(err error) ErrorKind{
+	var e *Error
+	if errors.As(err, &e){
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// NewValidationError reports an invalid field of a request.
+func NewValidationError(field string, message string) *Error{
+	return &Error{
+		Kind: KindValidation,
+		Code: CodeValidation,
+		Message: message,
+		Fields: []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// NewBadRequestError reports a request that can't be parsed.
+func NewBadRequestError(err error) *Error{
+	return &Error{Kind: KindBadRequest, Code: CodeBadRequest, Message: err.Error()}
+}
+
+// NewUnsupportedCurrencyError reports a currency without a known exchange rate.
+func NewUnsupportedCurrencyError(currency string) *Error{
+	return &Error{
+		Kind: KindValidation,
+		Code: CodeUnsupportedCurrency,
+		Message: "unsupported currency " + currency,
+	}
+}
+
+func NewNotFoundError(code string, message string) *Error{
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func NewConflictError(code string, message string) *Error{
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// NewUnavailableError reports a failed dependency, err is the cause.
+func NewUnavailableError(code string, message string, err error) *Error{
+	return &Error{Kind: KindUnavailable, Code: code, Message: message, Err: err}
+}
+
+var(
+	// ErrIdempotencyConflict is returned when an idempotency key is reused with a different request body.
+	ErrIdempotencyConflict = NewConflictError(CodeIdempotencyConflict, "idempotency key was already used with a different request")
+	// ErrUnsupportedCurrency matches errors about any currency without a known exchange rate.
+	ErrUnsupportedCurrency = NewUnsupportedCurrencyError("")
+	ErrInsufficientFunds = &Error{Kind: KindInsufficientFunds, Code: CodeInsufficientFunds, Message: "insufficient funds"}
+	ErrWalletNotFound = NewNotFoundError(CodeWalletNotFound, "user doesn't have the wallet")
+	ErrHoldNotFound = NewNotFoundError(CodeHoldNotFound, "hold not found")
+	ErrTransactionNotFound = NewNotFoundError(CodeTransactionNotFound, "transaction not found")
+	ErrTransferNotFound = NewNotFoundError(CodeTransferNotFound, "transfer not found")
+	ErrUnauthorized = &Error{Kind: KindUnauthorized, Code: CodeUnauthorized, Message: "missing or invalid credentials"}
+	ErrForbidden = &Error{Kind: KindForbidden, Code: CodeForbidden, Message: "the client lacks the scope of the request"}
+	ErrRateLimited = &Error{Kind: KindRateLimited, Code: CodeRateLimited, Message: "too many requests"}
+)
@@ -0,0 +1,20 @@
+package models
+
+// Statuses of the service and its dependencies.
+const(
+	StatusOK = "ok"
+	StatusUnavailable = "unavailable"
+	// StatusShuttingDown is reported once graceful shutdown begins.
+	StatusShuttingDown = "shutting_down"
+)
+
+type Dependency struct {
+	Status			string				`json:"status"`
+	Error			string				`json:"error,omitempty"`
+}
+
+// Readiness is ok when every dependency answers and the service isn't shutting down.
+type Readiness struct {
+	Status			string					`json:"status"`
+	Dependencies	map[string]Dependency	`json:"dependencies"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+const(
+	HoldHeld = "held"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+)
+
+type ReserveReq struct {
+	UserId    int       `json:"user_id"`
+	Amount    Money     `json:"amount"`
+	Currency  string	`json:"currency"`
+	Comment   string	`json:"comment"`
+}
+
+type HoldReq struct {
+	UserId    int       `json:"user_id"`
+	HoldId    int       `json:"hold_id"`
+}
+
+type HoldResp struct {
+	HoldId    int       `json:"hold_id"`
+	UserId    int       `json:"user_id"`
+	Amount    Money     `json:"amount"`
+	Currency  string	`json:"currency"`
+	Status    string    `json:"status"`
+	Balance   Money     `json:"balance"`
+	Held      Money     `json:"held"`
+}
+
+type Hold struct {
+	HoldId			int					`json:"hold_id" db:"hold_id"`
+	UserId          int                 `json:"user_id" db:"user_id"`
+	Amount			Money				`json:"amount" db:"amount"`
+	Currency		string				`json:"currency" db:"currency"`
+	Status			string				`json:"status" db:"status"`
+	Comment     	string				`json:"comment" db:"comment"`
+	Time	 		time.Time			`json:"time" db:"time"`
+}
+
+func (r *ReserveReq) Validate() error{
+	if r.UserId < 0 {
+		return NewValidationError("user_id", "user id can't be negative")
+	}
+	if r.Amount <= 0 {
+		return NewValidationError("amount", "reserved amount must be positive")
+	}
+	if err := validateAmount("amount", r.Amount, r.Currency); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (h *HoldReq) Validate() error{
+	if h.UserId < 0 {
+		return NewValidationError("user_id", "user id can't be negative")
+	}
+	if h.HoldId < 0 {
+		return NewValidationError("hold_id", "hold id can't be negative")
+	}
+	return nil
+}
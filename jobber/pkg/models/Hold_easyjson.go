@@ -0,0 +1,397 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package models
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels(in *jlexer.Lexer, out *ReserveReq) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "user_id":
+			out.UserId = int(in.Int())
+		case "amount":
+			(out.Amount).UnmarshalEasyJSON(in)
+		case "currency":
+			out.Currency = string(in.String())
+		case "comment":
+			out.Comment = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels(out *jwriter.Writer, in ReserveReq) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"user_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.UserId))
+	}
+	{
+		const prefix string = ",\"amount\":"
+		out.RawString(prefix)
+		(in.Amount).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"comment\":"
+		out.RawString(prefix)
+		out.String(string(in.Comment))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ReserveReq) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ReserveReq) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ReserveReq) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ReserveReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels(l, v)
+}
+func easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels1(in *jlexer.Lexer, out *HoldResp) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "hold_id":
+			out.HoldId = int(in.Int())
+		case "user_id":
+			out.UserId = int(in.Int())
+		case "amount":
+			(out.Amount).UnmarshalEasyJSON(in)
+		case "currency":
+			out.Currency = string(in.String())
+		case "status":
+			out.Status = string(in.String())
+		case "balance":
+			(out.Balance).UnmarshalEasyJSON(in)
+		case "held":
+			(out.Held).UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels1(out *jwriter.Writer, in HoldResp) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"hold_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.HoldId))
+	}
+	{
+		const prefix string = ",\"user_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.UserId))
+	}
+	{
+		const prefix string = ",\"amount\":"
+		out.RawString(prefix)
+		(in.Amount).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"status\":"
+		out.RawString(prefix)
+		out.String(string(in.Status))
+	}
+	{
+		const prefix string = ",\"balance\":"
+		out.RawString(prefix)
+		(in.Balance).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"held\":"
+		out.RawString(prefix)
+		(in.Held).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v HoldResp) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v HoldResp) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *HoldResp) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *HoldResp) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels1(l, v)
+}
+func easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels2(in *jlexer.Lexer, out *HoldReq) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "user_id":
+			out.UserId = int(in.Int())
+		case "hold_id":
+			out.HoldId = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels2(out *jwriter.Writer, in HoldReq) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"user_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.UserId))
+	}
+	{
+		const prefix string = ",\"hold_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.HoldId))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v HoldReq) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v HoldReq) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *HoldReq) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *HoldReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels2(l, v)
+}
+func easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels3(in *jlexer.Lexer, out *Hold) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "hold_id":
+			out.HoldId = int(in.Int())
+		case "user_id":
+			out.UserId = int(in.Int())
+		case "amount":
+			(out.Amount).UnmarshalEasyJSON(in)
+		case "currency":
+			out.Currency = string(in.String())
+		case "status":
+			out.Status = string(in.String())
+		case "comment":
+			out.Comment = string(in.String())
+		case "time":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Time).UnmarshalJSON(data))
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels3(out *jwriter.Writer, in Hold) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"hold_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.HoldId))
+	}
+	{
+		const prefix string = ",\"user_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.UserId))
+	}
+	{
+		const prefix string = ",\"amount\":"
+		out.RawString(prefix)
+		(in.Amount).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"status\":"
+		out.RawString(prefix)
+		out.String(string(in.Status))
+	}
+	{
+		const prefix string = ",\"comment\":"
+		out.RawString(prefix)
+		out.String(string(in.Comment))
+	}
+	{
+		const prefix string = ",\"time\":"
+		out.RawString(prefix)
+		out.Raw((in.Time).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Hold) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels3(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Hold) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson61f9bc27EncodeGithubComFedorkolmykowAvitojobPkgModels3(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Hold) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels3(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Hold) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson61f9bc27DecodeGithubComFedorkolmykowAvitojobPkgModels3(l, v)
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strconv"
+)
+
+// System accounts are the other side of every user change in the ledger. Money of a currency
+// is never created or lost: the postings of all accounts sum to zero.
+const(
+	// AccountExternal is the prefix of funding sources outside of the service, "external:Sberbank".
+	AccountExternal = "external"
+	AccountFees = "fees"
+	AccountWriteOffs = "writeoffs"
+	// AccountPayments receives captured holds.
+	AccountPayments = "payments"
+	// AccountFX balances both currencies of a transfer between wallets of different currencies.
+	AccountFX = "fx"
+	// AccountOpening holds the balances which existed before the ledger.
+	AccountOpening = "opening"
+	// AccountAdjustments balances the corrective transactions of reconciliation.
+	AccountAdjustments = "adjustments"
+)
+
+// ChangeAccounts are the system accounts a balance change can be booked against.
+var ChangeAccounts = []string{AccountExternal, AccountFees, AccountWriteOffs}
+
+// UserAccount names the ledger account of a user, one per currency wallet.
+func UserAccount(userId int) string{
+	return "user:" + strconv.Itoa(userId)
+}
+
+// ExternalAccount names the system account of a funding source.
+func ExternalAccount(source string) string{
+	if source == ""{
+		return AccountExternal
+	}
+	return AccountExternal + ":" + source
+}
+
+// Posting moves Amount to the account, a negative Amount moves it out. Postings
+// are grouped into entries, the postings of an entry sum to zero in every currency.
+type Posting struct {
+	PostingId		int					`json:"posting_id" db:"posting_id"`
+	EntryId			int					`json:"entry_id" db:"entry_id"`
+	Account			string				`json:"account" db:"account"`
+	Currency		string				`json:"currency" db:"currency"`
+	Amount			Money				`json:"amount" db:"amount"`
+	TransId			*int				`json:"trans_id,omitempty" db:"trans_id"`
+}
+
+// Imbalance is a nonzero sum of postings in a currency. EntryId is set
+// when the postings of one entry don't sum to zero.
+type Imbalance struct {
+	EntryId			*int				`json:"entry_id,omitempty" db:"entry_id"`
+	Currency		string				`json:"currency" db:"currency"`
+	Sum				Money				`json:"sum" db:"sum"`
+}
+
+type LedgerCheck struct {
+	Balanced		bool				`json:"balanced"`
+	Imbalances		[]Imbalance			`json:"imbalances"`
+}
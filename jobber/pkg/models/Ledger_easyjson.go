@@ -0,0 +1,327 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package models
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonEa415e17DecodeGithubComFedorkolmykowAvitojobPkgModels(in *jlexer.Lexer, out *Posting) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "posting_id":
+			out.PostingId = int(in.Int())
+		case "entry_id":
+			out.EntryId = int(in.Int())
+		case "account":
+			out.Account = string(in.String())
+		case "currency":
+			out.Currency = string(in.String())
+		case "amount":
+			(out.Amount).UnmarshalEasyJSON(in)
+		case "trans_id":
+			if in.IsNull() {
+				in.Skip()
+				out.TransId = nil
+			} else {
+				if out.TransId == nil {
+					out.TransId = new(int)
+				}
+				*out.TransId = int(in.Int())
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonEa415e17EncodeGithubComFedorkolmykowAvitojobPkgModels(out *jwriter.Writer, in Posting) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"posting_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.PostingId))
+	}
+	{
+		const prefix string = ",\"entry_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.EntryId))
+	}
+	{
+		const prefix string = ",\"account\":"
+		out.RawString(prefix)
+		out.String(string(in.Account))
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"amount\":"
+		out.RawString(prefix)
+		(in.Amount).MarshalEasyJSON(out)
+	}
+	if in.TransId != nil {
+		const prefix string = ",\"trans_id\":"
+		out.RawString(prefix)
+		out.Int(int(*in.TransId))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Posting) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonEa415e17EncodeGithubComFedorkolmykowAvitojobPkgModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Posting) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonEa415e17EncodeGithubComFedorkolmykowAvitojobPkgModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Posting) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonEa415e17DecodeGithubComFedorkolmykowAvitojobPkgModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Posting) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonEa415e17DecodeGithubComFedorkolmykowAvitojobPkgModels(l, v)
+}
+func easyjsonEa415e17DecodeGithubComFedorkolmykowAvitojobPkgModels1(in *jlexer.Lexer, out *LedgerCheck) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "balanced":
+			out.Balanced = bool(in.Bool())
+		case "imbalances":
+			if in.IsNull() {
+				in.Skip()
+				out.Imbalances = nil
+			} else {
+				in.Delim('[')
+				if out.Imbalances == nil {
+					if !in.IsDelim(']') {
+						out.Imbalances = make([]Imbalance, 0, 2)
+					} else {
+						out.Imbalances = []Imbalance{}
+					}
+				} else {
+					out.Imbalances = (out.Imbalances)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 Imbalance
+					(v1).UnmarshalEasyJSON(in)
+					out.Imbalances = append(out.Imbalances, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonEa415e17EncodeGithubComFedorkolmykowAvitojobPkgModels1(out *jwriter.Writer, in LedgerCheck) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"balanced\":"
+		out.RawString(prefix[1:])
+		out.Bool(bool(in.Balanced))
+	}
+	{
+		const prefix string = ",\"imbalances\":"
+		out.RawString(prefix)
+		if in.Imbalances == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.Imbalances {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				(v3).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v LedgerCheck) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonEa415e17EncodeGithubComFedorkolmykowAvitojobPkgModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v LedgerCheck) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonEa415e17EncodeGithubComFedorkolmykowAvitojobPkgModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *LedgerCheck) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonEa415e17DecodeGithubComFedorkolmykowAvitojobPkgModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *LedgerCheck) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonEa415e17DecodeGithubComFedorkolmykowAvitojobPkgModels1(l, v)
+}
+func easyjsonEa415e17DecodeGithubComFedorkolmykowAvitojobPkgModels2(in *jlexer.Lexer, out *Imbalance) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "entry_id":
+			if in.IsNull() {
+				in.Skip()
+				out.EntryId = nil
+			} else {
+				if out.EntryId == nil {
+					out.EntryId = new(int)
+				}
+				*out.EntryId = int(in.Int())
+			}
+		case "currency":
+			out.Currency = string(in.String())
+		case "sum":
+			(out.Sum).UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonEa415e17EncodeGithubComFedorkolmykowAvitojobPkgModels2(out *jwriter.Writer, in Imbalance) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if in.EntryId != nil {
+		const prefix string = ",\"entry_id\":"
+		first = false
+		out.RawString(prefix[1:])
+		out.Int(int(*in.EntryId))
+	}
+	{
+		const prefix string = ",\"currency\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"sum\":"
+		out.RawString(prefix)
+		(in.Sum).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Imbalance) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonEa415e17EncodeGithubComFedorkolmykowAvitojobPkgModels2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Imbalance) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonEa415e17EncodeGithubComFedorkolmykowAvitojobPkgModels2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Imbalance) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonEa415e17DecodeGithubComFedorkolmykowAvitojobPkgModels2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Imbalance) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonEa415e17DecodeGithubComFedorkolmykowAvitojobPkgModels2(l, v)
+}
@@ -0,0 +1,40 @@
+package models
+
+// LogFields of requests name the same things the same way in every log line:
+// user_id, amount, currency. Idempotency keys and comments aren't logged.
+
+func (r *ChangeBalanceReq) LogFields() map[string]interface{}{
+	return map[string]interface{}{"user_id": r.UserId, "amount": r.Change, "currency": r.Currency, "source": r.Source}
+}
+
+func (r *TransferReq) LogFields() map[string]interface{}{
+	return map[string]interface{}{"user_id": r.UserId, "amount": r.Change, "currency": r.Currency, "target_id": r.TargetId}
+}
+
+func (r *ReserveReq) LogFields() map[string]interface{}{
+	return map[string]interface{}{"user_id": r.UserId, "amount": r.Amount, "currency": r.Currency}
+}
+
+func (r *HoldReq) LogFields() map[string]interface{}{
+	return map[string]interface{}{"user_id": r.UserId, "hold_id": r.HoldId}
+}
+
+func (r *RefundReq) LogFields() map[string]interface{}{
+	return map[string]interface{}{"user_id": r.UserId, "amount": r.Amount, "trans_id": r.TransId}
+}
+
+func (r *GetBalanceReq) LogFields() map[string]interface{}{
+	return map[string]interface{}{"user_id": r.UserId, "currency": r.Currency}
+}
+
+func (r *GetBalancesReq) LogFields() map[string]interface{}{
+	return map[string]interface{}{"user_ids": r.UserIds, "currency": r.Currency}
+}
+
+func (r *GetTransactionsReq) LogFields() map[string]interface{}{
+	return map[string]interface{}{"user_id": r.UserId, "currency": r.Currency}
+}
+
+func (r *GetTransferReq) LogFields() map[string]interface{}{
+	return map[string]interface{}{"transfer_id": r.TransferId}
+}
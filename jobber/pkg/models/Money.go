@@ -0,0 +1,171 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// MoneyDecimals is the number of fraction digits kept in Money. Amounts are stored in kopecks (cents).
+const MoneyDecimals = 2
+
+const moneyScale = 100
+
+// currencyDecimals lists currencies whose minor unit differs from MoneyDecimals.
+var currencyDecimals = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"ISK": 0,
+	"HUF": 0,
+}
+
+// Money is an amount in minor units of its currency. In JSON it is a decimal number of major units.
+type Money int64
+
+// CurrencyDecimals returns the number of fraction digits the currency allows.
+func CurrencyDecimals(currency string) int {
+	if d, ok := currencyDecimals[currency]; ok {
+		return d
+	}
+	return MoneyDecimals
+}
+
+// ParseMoney parses a decimal amount of major units. Amounts with more than MoneyDecimals fraction digits are rejected.
+func ParseMoney(s string) (Money, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(moneyScale))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("amount %s has more than %d decimal places", s, MoneyDecimals)
+	}
+	if !r.Num().IsInt64() {
+		return 0, fmt.Errorf("amount %s is too large", s)
+	}
+	return Money(r.Num().Int64()), nil
+}
+
+// currencyUnit is the smallest amount of currency expressed in Money units.
+func currencyUnit(currency string) int64 {
+	unit := int64(1)
+	for i := CurrencyDecimals(currency); i < MoneyDecimals; i++ {
+		unit *= 10
+	}
+	return unit
+}
+
+// FitsCurrency reports whether the amount has no more fraction digits than the currency allows.
+func (m Money) FitsCurrency(currency string) bool {
+	return int64(m)%currencyUnit(currency) == 0
+}
+
+// Convert multiplies the amount by rate and rounds the result half away from zero
+// to the minor unit of currency.
+func (m Money) Convert(rate float64, currency string) (Money, error) {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'g', -1, 64))
+	if !ok {
+		return 0, fmt.Errorf("invalid rate %v", rate)
+	}
+	unit := currencyUnit(currency)
+	r.Mul(r, new(big.Rat).SetInt64(int64(m)))
+	r.Quo(r, new(big.Rat).SetInt64(unit))
+	q := roundRat(r)
+	q.Mul(q, big.NewInt(unit))
+	if !q.IsInt64() {
+		return 0, errors.New("converted amount is too large")
+	}
+	return Money(q.Int64()), nil
+}
+
+// MulDiv returns m * num / den rounded half away from zero.
+func (m Money) MulDiv(num, den Money) Money {
+	r := new(big.Rat).SetFrac(big.NewInt(int64(num)), big.NewInt(int64(den)))
+	r.Mul(r, new(big.Rat).SetInt64(int64(m)))
+	return Money(roundRat(r).Int64())
+}
+
+// roundRat rounds r half away from zero.
+func roundRat(r *big.Rat) *big.Int {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Abs(rem).Lsh(rem, 1).Cmp(r.Denom()) >= 0 {
+		if r.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func (m Money) String() string {
+	v := int64(m)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	if v%moneyScale == 0 {
+		return sign + strconv.FormatInt(v/moneyScale, 10)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/moneyScale, v%moneyScale)
+}
+
+func (m Money) MarshalEasyJSON(w *jwriter.Writer) {
+	w.RawString(m.String())
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalEasyJSON accepts both JSON numbers and numeric strings.
+func (m *Money) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	num := l.JsonNumber()
+	if !l.Ok() || num == "" {
+		return
+	}
+	v, err := ParseMoney(string(num))
+	if err != nil {
+		l.AddError(err)
+		return
+	}
+	*m = v
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	l := jlexer.Lexer{Data: data}
+	m.UnmarshalEasyJSON(&l)
+	return l.Error()
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		*m = Money(v)
+	case []byte:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return err
+		}
+		*m = Money(n)
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*m = Money(n)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
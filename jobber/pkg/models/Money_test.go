@@ -0,0 +1,86 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestParseMoney(t *testing.T){
+	cases := []struct{
+		In   string
+		Out  Money
+		Err  bool
+	}{
+		{In: "200", Out: 20000},
+		{In: "0.1", Out: 10},
+		{In: "-0.01", Out: -1},
+		{In: "1e2", Out: 10000},
+		{In: "0.001", Err: true},
+		{In: "abc", Err: true},
+		{In: "100000000000000000000", Err: true},
+	}
+	for num, c := range cases{
+		out, err := ParseMoney(c.In)
+		if (err != nil) != c.Err{
+			t.Errorf("[%d] unexpected error: %v", num, err)
+			continue
+		}
+		if out != c.Out{
+			t.Errorf("[%d] unexpected result: %d, expected: %d", num, out, c.Out)
+		}
+	}
+}
+
+func TestMoneySumIsExact(t *testing.T){
+	a, _ := ParseMoney("0.1")
+	b, _ := ParseMoney("0.2")
+	c, _ := ParseMoney("0.3")
+	if a+b != c{
+		t.Errorf("0.1 + 0.2 = %s", a+b)
+	}
+}
+
+func TestMoneyString(t *testing.T){
+	cases := map[Money]string{
+		20000: "200",
+		20050: "200.50",
+		-1:    "-0.01",
+		0:     "0",
+	}
+	for in, out := range cases{
+		if in.String() != out{
+			t.Errorf("unexpected result: %s, expected: %s", in.String(), out)
+		}
+	}
+}
+
+func TestMoneyConvert(t *testing.T){
+	cases := []struct{
+		In       Money
+		Rate     float64
+		Currency string
+		Out      Money
+	}{
+		{In: 10000, Rate: 0.0135, Currency: "USD", Out: 135},
+		{In: 1, Rate: 0.5, Currency: "USD", Out: 1},
+		{In: -1, Rate: 0.5, Currency: "USD", Out: -1},
+		{In: 10000, Rate: 1.4234, Currency: "JPY", Out: 14200},
+	}
+	for num, c := range cases{
+		out, err := c.In.Convert(c.Rate, c.Currency)
+		if err != nil{
+			t.Errorf("[%d] unexpected error: %v", num, err)
+			continue
+		}
+		if out != c.Out{
+			t.Errorf("[%d] unexpected result: %d, expected: %d", num, out, c.Out)
+		}
+	}
+}
+
+func TestMoneyUnmarshalRejectsExtraPrecision(t *testing.T){
+	req := &ChangeBalanceReq{}
+	err := req.UnmarshalJSON([]byte(`{"change":10.005}`))
+	if err == nil{
+		t.Errorf("expected error for amount with 3 decimal places, got %d", req.Change)
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Kinds of discrepancies found by reconciliation.
+const(
+	// DiscrepancyBalance is a wallet balance which isn't the sum of the changes of its transactions.
+	DiscrepancyBalance = "balance_mismatch"
+	// DiscrepancyChain is a transaction which doesn't start at the balance the previous one left,
+	// or a wallet balance other than the one the last transaction left when TransId is nil.
+	DiscrepancyChain = "broken_chain"
+	// DiscrepancyLedger is a user account whose postings don't sum to the wallet balance.
+	DiscrepancyLedger = "ledger_mismatch"
+)
+
+// SourceReconciliation is the source of corrective transactions.
+const SourceReconciliation = "reconciliation"
+
+type Discrepancy struct {
+	UserId			int					`json:"user_id"`
+	Currency		string				`json:"currency"`
+	Kind			string				`json:"kind"`
+	TransId			*int				`json:"trans_id,omitempty"`
+	Expected		Money				`json:"expected"`
+	Actual			Money				`json:"actual"`
+	Repaired		bool				`json:"repaired"`
+	// Correction is the corrective transaction recorded for a broken chain.
+	Correction		*int				`json:"correction,omitempty"`
+}
+
+type ReconciliationReport struct {
+	Started			time.Time			`json:"started"`
+	Finished		time.Time			`json:"finished"`
+	Repair			bool				`json:"repair"`
+	Wallets			int					`json:"wallets"`
+	Discrepancies	[]Discrepancy		`json:"discrepancies"`
+}
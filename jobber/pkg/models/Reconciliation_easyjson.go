@@ -0,0 +1,280 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package models
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson7db02fffDecodeGithubComFedorkolmykowAvitojobPkgModels(in *jlexer.Lexer, out *ReconciliationReport) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "started":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Started).UnmarshalJSON(data))
+			}
+		case "finished":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Finished).UnmarshalJSON(data))
+			}
+		case "repair":
+			out.Repair = bool(in.Bool())
+		case "wallets":
+			out.Wallets = int(in.Int())
+		case "discrepancies":
+			if in.IsNull() {
+				in.Skip()
+				out.Discrepancies = nil
+			} else {
+				in.Delim('[')
+				if out.Discrepancies == nil {
+					if !in.IsDelim(']') {
+						out.Discrepancies = make([]Discrepancy, 0, 0)
+					} else {
+						out.Discrepancies = []Discrepancy{}
+					}
+				} else {
+					out.Discrepancies = (out.Discrepancies)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 Discrepancy
+					(v1).UnmarshalEasyJSON(in)
+					out.Discrepancies = append(out.Discrepancies, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson7db02fffEncodeGithubComFedorkolmykowAvitojobPkgModels(out *jwriter.Writer, in ReconciliationReport) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"started\":"
+		out.RawString(prefix[1:])
+		out.Raw((in.Started).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"finished\":"
+		out.RawString(prefix)
+		out.Raw((in.Finished).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"repair\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.Repair))
+	}
+	{
+		const prefix string = ",\"wallets\":"
+		out.RawString(prefix)
+		out.Int(int(in.Wallets))
+	}
+	{
+		const prefix string = ",\"discrepancies\":"
+		out.RawString(prefix)
+		if in.Discrepancies == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.Discrepancies {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				(v3).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ReconciliationReport) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson7db02fffEncodeGithubComFedorkolmykowAvitojobPkgModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ReconciliationReport) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson7db02fffEncodeGithubComFedorkolmykowAvitojobPkgModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ReconciliationReport) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson7db02fffDecodeGithubComFedorkolmykowAvitojobPkgModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ReconciliationReport) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson7db02fffDecodeGithubComFedorkolmykowAvitojobPkgModels(l, v)
+}
+func easyjson7db02fffDecodeGithubComFedorkolmykowAvitojobPkgModels1(in *jlexer.Lexer, out *Discrepancy) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "user_id":
+			out.UserId = int(in.Int())
+		case "currency":
+			out.Currency = string(in.String())
+		case "kind":
+			out.Kind = string(in.String())
+		case "trans_id":
+			if in.IsNull() {
+				in.Skip()
+				out.TransId = nil
+			} else {
+				if out.TransId == nil {
+					out.TransId = new(int)
+				}
+				*out.TransId = int(in.Int())
+			}
+		case "expected":
+			(out.Expected).UnmarshalEasyJSON(in)
+		case "actual":
+			(out.Actual).UnmarshalEasyJSON(in)
+		case "repaired":
+			out.Repaired = bool(in.Bool())
+		case "correction":
+			if in.IsNull() {
+				in.Skip()
+				out.Correction = nil
+			} else {
+				if out.Correction == nil {
+					out.Correction = new(int)
+				}
+				*out.Correction = int(in.Int())
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson7db02fffEncodeGithubComFedorkolmykowAvitojobPkgModels1(out *jwriter.Writer, in Discrepancy) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"user_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.UserId))
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"kind\":"
+		out.RawString(prefix)
+		out.String(string(in.Kind))
+	}
+	if in.TransId != nil {
+		const prefix string = ",\"trans_id\":"
+		out.RawString(prefix)
+		out.Int(int(*in.TransId))
+	}
+	{
+		const prefix string = ",\"expected\":"
+		out.RawString(prefix)
+		(in.Expected).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"actual\":"
+		out.RawString(prefix)
+		(in.Actual).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"repaired\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.Repaired))
+	}
+	if in.Correction != nil {
+		const prefix string = ",\"correction\":"
+		out.RawString(prefix)
+		out.Int(int(*in.Correction))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Discrepancy) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson7db02fffEncodeGithubComFedorkolmykowAvitojobPkgModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Discrepancy) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson7db02fffEncodeGithubComFedorkolmykowAvitojobPkgModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Discrepancy) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson7db02fffDecodeGithubComFedorkolmykowAvitojobPkgModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Discrepancy) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson7db02fffDecodeGithubComFedorkolmykowAvitojobPkgModels1(l, v)
+}
@@ -0,0 +1,34 @@
+package models
+
+type RefundReq struct {
+	UserId    int       `json:"user_id"`
+	TransId   int       `json:"trans_id"`
+	Amount    Money     `json:"amount"`
+	Comment   string	`json:"comment"`
+	IdempotencyKey string `json:"-"`
+	// Authorize, when set, is called with the transaction to refund before anything is written.
+	Authorize func(orig *Transaction) error `json:"-"`
+}
+
+type RefundResp struct {
+	TransId   int       		`json:"trans_id"`
+	Amount    Money     		`json:"amount"`
+	Remaining Money     		`json:"remaining"`
+	Balances  []ChangeBalanceResp `json:"balances"`
+}
+
+func (r *RefundReq) Validate() error{
+	if r.UserId < 0 {
+		return NewValidationError("user_id", "user id can't be negative")
+	}
+	if r.TransId < 0 {
+		return NewValidationError("trans_id", "transaction id can't be negative")
+	}
+	if r.Amount < 0 {
+		return NewValidationError("amount", "refund amount can't be negative")
+	}
+	if len(r.IdempotencyKey) > MaxIdempotencyKeyLen {
+		return NewValidationError("idempotency_key", "idempotency key is too long")
+	}
+	return nil
+}
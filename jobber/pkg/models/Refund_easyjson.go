@@ -0,0 +1,225 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package models
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson8bd63be6DecodeGithubComFedorkolmykowAvitojobPkgModels(in *jlexer.Lexer, out *RefundResp) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "trans_id":
+			out.TransId = int(in.Int())
+		case "amount":
+			(out.Amount).UnmarshalEasyJSON(in)
+		case "remaining":
+			(out.Remaining).UnmarshalEasyJSON(in)
+		case "balances":
+			if in.IsNull() {
+				in.Skip()
+				out.Balances = nil
+			} else {
+				in.Delim('[')
+				if out.Balances == nil {
+					if !in.IsDelim(']') {
+						out.Balances = make([]ChangeBalanceResp, 0, 4)
+					} else {
+						out.Balances = []ChangeBalanceResp{}
+					}
+				} else {
+					out.Balances = (out.Balances)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 ChangeBalanceResp
+					(v1).UnmarshalEasyJSON(in)
+					out.Balances = append(out.Balances, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson8bd63be6EncodeGithubComFedorkolmykowAvitojobPkgModels(out *jwriter.Writer, in RefundResp) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"trans_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.TransId))
+	}
+	{
+		const prefix string = ",\"amount\":"
+		out.RawString(prefix)
+		(in.Amount).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"remaining\":"
+		out.RawString(prefix)
+		(in.Remaining).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"balances\":"
+		out.RawString(prefix)
+		if in.Balances == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.Balances {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				(v3).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RefundResp) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson8bd63be6EncodeGithubComFedorkolmykowAvitojobPkgModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RefundResp) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson8bd63be6EncodeGithubComFedorkolmykowAvitojobPkgModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RefundResp) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson8bd63be6DecodeGithubComFedorkolmykowAvitojobPkgModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RefundResp) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson8bd63be6DecodeGithubComFedorkolmykowAvitojobPkgModels(l, v)
+}
+func easyjson8bd63be6DecodeGithubComFedorkolmykowAvitojobPkgModels1(in *jlexer.Lexer, out *RefundReq) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "user_id":
+			out.UserId = int(in.Int())
+		case "trans_id":
+			out.TransId = int(in.Int())
+		case "amount":
+			(out.Amount).UnmarshalEasyJSON(in)
+		case "comment":
+			out.Comment = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson8bd63be6EncodeGithubComFedorkolmykowAvitojobPkgModels1(out *jwriter.Writer, in RefundReq) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"user_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.UserId))
+	}
+	{
+		const prefix string = ",\"trans_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.TransId))
+	}
+	{
+		const prefix string = ",\"amount\":"
+		out.RawString(prefix)
+		(in.Amount).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"comment\":"
+		out.RawString(prefix)
+		out.String(string(in.Comment))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RefundReq) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson8bd63be6EncodeGithubComFedorkolmykowAvitojobPkgModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RefundReq) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson8bd63be6EncodeGithubComFedorkolmykowAvitojobPkgModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RefundReq) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson8bd63be6DecodeGithubComFedorkolmykowAvitojobPkgModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RefundReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson8bd63be6DecodeGithubComFedorkolmykowAvitojobPkgModels1(l, v)
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+type GetTransferReq struct {
+	TransferId	int		`json:"transfer_id"`
+}
+
+// Transfer links the two legs written to Transactions by one transfer.
+type Transfer struct {
+	TransferId		int					`json:"transfer_id" db:"transfer_id"`
+	SourceId		int					`json:"source_id" db:"source_id"`
+	TargetId		int					`json:"target_id" db:"target_id"`
+	Amount			Money				`json:"amount" db:"amount"`
+	Currency		string				`json:"currency" db:"currency"`
+	TargetAmount	Money				`json:"target_amount" db:"target_amount"`
+	TargetCurrency	string				`json:"target_currency" db:"target_currency"`
+	Rate			float64				`json:"rate" db:"rate"`
+	Comment     	string				`json:"comment" db:"comment"`
+	Time	 		time.Time			`json:"time" db:"time"`
+	Legs			[]Transaction		`json:"legs" db:"-"`
+	Refunds			[]Transaction		`json:"refunds" db:"-"`
+}
+
+func (g *GetTransferReq) Validate() error{
+	if g.TransferId < 0 {
+		return NewValidationError("transfer_id", "transfer id can't be negative")
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package models
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson334d5195DecodeGithubComFedorkolmykowAvitojobPkgModels(in *jlexer.Lexer, out *Transfer) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "transfer_id":
+			out.TransferId = int(in.Int())
+		case "source_id":
+			out.SourceId = int(in.Int())
+		case "target_id":
+			out.TargetId = int(in.Int())
+		case "amount":
+			(out.Amount).UnmarshalEasyJSON(in)
+		case "currency":
+			out.Currency = string(in.String())
+		case "target_amount":
+			(out.TargetAmount).UnmarshalEasyJSON(in)
+		case "target_currency":
+			out.TargetCurrency = string(in.String())
+		case "rate":
+			out.Rate = float64(in.Float64())
+		case "comment":
+			out.Comment = string(in.String())
+		case "time":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Time).UnmarshalJSON(data))
+			}
+		case "legs":
+			if in.IsNull() {
+				in.Skip()
+				out.Legs = nil
+			} else {
+				in.Delim('[')
+				if out.Legs == nil {
+					if !in.IsDelim(']') {
+						out.Legs = make([]Transaction, 0, 0)
+					} else {
+						out.Legs = []Transaction{}
+					}
+				} else {
+					out.Legs = (out.Legs)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 Transaction
+					(v1).UnmarshalEasyJSON(in)
+					out.Legs = append(out.Legs, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "refunds":
+			if in.IsNull() {
+				in.Skip()
+				out.Refunds = nil
+			} else {
+				in.Delim('[')
+				if out.Refunds == nil {
+					if !in.IsDelim(']') {
+						out.Refunds = make([]Transaction, 0, 0)
+					} else {
+						out.Refunds = []Transaction{}
+					}
+				} else {
+					out.Refunds = (out.Refunds)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v2 Transaction
+					(v2).UnmarshalEasyJSON(in)
+					out.Refunds = append(out.Refunds, v2)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson334d5195EncodeGithubComFedorkolmykowAvitojobPkgModels(out *jwriter.Writer, in Transfer) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"transfer_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.TransferId))
+	}
+	{
+		const prefix string = ",\"source_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.SourceId))
+	}
+	{
+		const prefix string = ",\"target_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.TargetId))
+	}
+	{
+		const prefix string = ",\"amount\":"
+		out.RawString(prefix)
+		(in.Amount).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"target_amount\":"
+		out.RawString(prefix)
+		(in.TargetAmount).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"target_currency\":"
+		out.RawString(prefix)
+		out.String(string(in.TargetCurrency))
+	}
+	{
+		const prefix string = ",\"rate\":"
+		out.RawString(prefix)
+		out.Float64(float64(in.Rate))
+	}
+	{
+		const prefix string = ",\"comment\":"
+		out.RawString(prefix)
+		out.String(string(in.Comment))
+	}
+	{
+		const prefix string = ",\"time\":"
+		out.RawString(prefix)
+		out.Raw((in.Time).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"legs\":"
+		out.RawString(prefix)
+		if in.Legs == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v3, v4 := range in.Legs {
+				if v3 > 0 {
+					out.RawByte(',')
+				}
+				(v4).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"refunds\":"
+		out.RawString(prefix)
+		if in.Refunds == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v5, v6 := range in.Refunds {
+				if v5 > 0 {
+					out.RawByte(',')
+				}
+				(v6).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Transfer) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson334d5195EncodeGithubComFedorkolmykowAvitojobPkgModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Transfer) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson334d5195EncodeGithubComFedorkolmykowAvitojobPkgModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Transfer) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson334d5195DecodeGithubComFedorkolmykowAvitojobPkgModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Transfer) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson334d5195DecodeGithubComFedorkolmykowAvitojobPkgModels(l, v)
+}
+func easyjson334d5195DecodeGithubComFedorkolmykowAvitojobPkgModels1(in *jlexer.Lexer, out *GetTransferReq) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "transfer_id":
+			out.TransferId = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson334d5195EncodeGithubComFedorkolmykowAvitojobPkgModels1(out *jwriter.Writer, in GetTransferReq) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"transfer_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.TransferId))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GetTransferReq) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson334d5195EncodeGithubComFedorkolmykowAvitojobPkgModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GetTransferReq) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson334d5195EncodeGithubComFedorkolmykowAvitojobPkgModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GetTransferReq) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson334d5195DecodeGithubComFedorkolmykowAvitojobPkgModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GetTransferReq) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson334d5195DecodeGithubComFedorkolmykowAvitojobPkgModels1(l, v)
+}
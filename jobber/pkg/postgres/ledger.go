@@ -0,0 +1,261 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
+	"github.com/fedorkolmykow/avitojob/pkg/metrics"
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+const(
+	InsertAccount = `INSERT INTO Accounts (name, user_id, currency) VALUES ($1, $2, $3) ON CONFLICT (name, currency) DO NOTHING;`
+	SelectAccount = `SELECT account_id FROM Accounts WHERE name=$1 AND currency=$2;`
+	InsertEntry = `INSERT INTO LedgerEntries (kind, time) VALUES ($1, $2) RETURNING entry_id;`
+	InsertPosting = `INSERT INTO Postings (entry_id, account_id, amount, trans_id) VALUES ($1, $2, $3, $4);`
+	SelectEntrySystemAccounts = `SELECT a.name, a.currency FROM Postings p JOIN Accounts a ON a.account_id = p.account_id
+                     WHERE a.user_id IS NULL AND p.entry_id = (SELECT entry_id FROM Postings WHERE trans_id=$1);`
+	SelectUnbalancedCurrencies = `SELECT a.currency, SUM(p.amount)::bigint AS sum FROM Postings p JOIN Accounts a ON a.account_id = p.account_id
+                     GROUP BY a.currency HAVING SUM(p.amount) <> 0 ORDER BY a.currency;`
+	SelectUnbalancedEntries = `SELECT p.entry_id, a.currency, SUM(p.amount)::bigint AS sum FROM Postings p JOIN Accounts a ON a.account_id = p.account_id
+                     GROUP BY p.entry_id, a.currency HAVING SUM(p.amount) <> 0 ORDER BY p.entry_id, a.currency;`
+)
+
+// Kinds of ledger entries.
+const(
+	entryChange = "change"
+	entryTransfer = "transfer"
+	entryCapture = "capture"
+	entryRefund = "refund"
+	entryCorrection = "correction"
+)
+
+// ledgerLeg is one side of an entry. A user leg changes the wallet of Trans.UserId by Trans.Change
+// and is written to Transactions, a system leg only moves Amount on the System account.
+type ledgerLeg struct{
+	Trans *m.Transaction
+	System string
+	Currency string
+	Amount m.Money
+	// OpenWallet lets a user leg open the wallet if the user doesn't have it yet.
+	OpenWallet bool
+	// Released is the held money spent by a user leg, it is returned before the funds check.
+	Released m.Money
+	// Recorded is a change the wallet already has, only its transaction is written.
+	Recorded bool
+	// Balance is the wallet balance after a user leg.
+	Balance m.Money
+}
+
+func userLeg(trans *m.Transaction) *ledgerLeg{
+	return &ledgerLeg{Trans: trans, Currency: trans.Currency, Amount: trans.Change}
+}
+
+func systemLeg(account string, currency string, amount m.Money) *ledgerLeg{
+	return &ledgerLeg{System: account, Currency: currency, Amount: amount}
+}
+
+type ledgerEntry struct{
+	Kind string
+	Legs []*ledgerLeg
+}
+
+// sums returns the sum of the legs in every currency.
+func (e *ledgerEntry) sums() map[string]m.Money{
+	sums := map[string]m.Money{}
+	for _, leg := range e.Legs{
+		sums[leg.Currency] += leg.Amount
+	}
+	return sums
+}
+
+// check fails unless the legs sum to zero in every currency.
+func (e *ledgerEntry) check() error{
+	sums := e.sums()
+	currencies := make([]string, 0, len(sums))
+	for cur := range sums{
+		currencies = append(currencies, cur)
+	}
+	sort.Strings(currencies)
+	for _, cur := range currencies{
+		if sums[cur] != 0{
+			return fmt.Errorf("unbalanced %s entry: %s legs sum to %d", e.Kind, cur, int64(sums[cur]))
+		}
+	}
+	return nil
+}
+
+// balance appends the system legs which bring every currency of the entry to zero.
+// accounts names the system account of each currency.
+func (e *ledgerEntry) balance(accounts map[string]string) error{
+	sums := e.sums()
+	currencies := make([]string, 0, len(sums))
+	for cur := range sums{
+		currencies = append(currencies, cur)
+	}
+	sort.Strings(currencies)
+	for _, cur := range currencies{
+		if sums[cur] == 0{
+			continue
+		}
+		account, ok := accounts[cur]
+		if !ok{
+			return fmt.Errorf("no system account to balance %s %s entry", cur, e.Kind)
+		}
+		e.Legs = append(e.Legs, systemLeg(account, cur, -sums[cur]))
+	}
+	return nil
+}
+
+// post books a balanced entry: user legs change wallets and are written to Transactions, then every
+// leg becomes a posting. Postgres checks the balance of the entry once more at commit.
+func post(ctx context.Context, tx *sqlx.Tx, entry *ledgerEntry) (err error){
+	err = entry.check()
+	if err != nil{
+		return
+	}
+	var entryId int
+	err = tx.QueryRowContext(ctx, InsertEntry, entry.Kind, time.Now()).Scan(&entryId)
+	if err != nil{
+		return
+	}
+	for _, leg := range entry.Legs{
+		var accountId int
+		var transId, userId *int
+		name := leg.System
+		if leg.Trans != nil{
+			err = applyUserLeg(ctx, tx, leg)
+			if err != nil{
+				return
+			}
+			name = m.UserAccount(leg.Trans.UserId)
+			transId, userId = &leg.Trans.TransId, &leg.Trans.UserId
+		}
+		accountId, err = account(ctx, tx, name, userId, leg.Currency)
+		if err != nil{
+			return
+		}
+		_, err = tx.ExecContext(ctx, InsertPosting, entryId, accountId, leg.Amount, transId)
+		if err != nil{
+			return
+		}
+	}
+	logging.FromContext(ctx).WithFields(log.Fields{"entry_id": entryId, "kind": entry.Kind}).Trace("posted entry")
+	return
+}
+
+// countPosted adds a committed entry to the business metrics, entry is nil when nothing was posted.
+func countPosted(entry *ledgerEntry){
+	if entry == nil{
+		return
+	}
+	if entry.Kind == entryTransfer{
+		metrics.Transfers.Inc()
+	}
+	for _, leg := range entry.Legs{
+		switch{
+		case leg.Trans == nil:
+		case leg.Amount > 0:
+			metrics.Credited.WithLabelValues(leg.Currency).Add(float64(leg.Amount))
+		case leg.Amount < 0:
+			metrics.Debited.WithLabelValues(leg.Currency).Add(float64(-leg.Amount))
+		}
+	}
+}
+
+// applyUserLeg changes the wallet of the leg. Money held by reservations can't be spent.
+func applyUserLeg(ctx context.Context, tx *sqlx.Tx, leg *ledgerLeg) (err error){
+	var held m.Money
+	tr := leg.Trans
+	if leg.Recorded{
+		leg.Balance = tr.InitialBalance + tr.Change
+		return insertTransaction(ctx, tx, tr)
+	}
+	if leg.OpenWallet{
+		var existed bool
+		existed, err = createWallet(ctx, tx, tr)
+		if err != nil{
+			return
+		}
+		if !existed{
+			leg.Balance = tr.Change
+			return
+		}
+	}
+	err = tx.QueryRowContext(ctx, SelectUserBalance, tr.UserId, tr.Currency).Scan(&tr.InitialBalance, &held)
+	if err == sql.ErrNoRows{
+		return m.ErrWalletNotFound
+	}
+	if err != nil{
+		return
+	}
+	leg.Balance, err = changeBalance(ctx, tx, tr, held - leg.Released)
+	return
+}
+
+// account returns the id of the account, which is opened on first use.
+func account(ctx context.Context, tx *sqlx.Tx, name string, userId *int, currency string) (accountId int, err error){
+	_, err = tx.ExecContext(ctx, InsertAccount, name, userId, currency)
+	if err != nil{
+		return
+	}
+	err = tx.QueryRowContext(ctx, SelectAccount, name, currency).Scan(&accountId)
+	return
+}
+
+// entryAccounts returns the system accounts of the entry of a transaction by currency. Transactions
+// made before the ledger have no entry, their balances were booked against AccountOpening.
+func entryAccounts(ctx context.Context, tx *sqlx.Tx, transId int, currencies []string) (accounts map[string]string, err error){
+	rows := []struct{
+		Name string `db:"name"`
+		Currency string `db:"currency"`
+	}{}
+	err = tx.SelectContext(ctx, &rows, SelectEntrySystemAccounts, transId)
+	if err != nil{
+		return
+	}
+	accounts = map[string]string{}
+	if len(rows) == 0{
+		for _, cur := range currencies{
+			accounts[cur] = m.AccountOpening
+		}
+		return
+	}
+	for _, row := range rows{
+		accounts[row.Currency] = row.Name
+	}
+	return
+}
+
+// CheckLedger finds currencies and entries whose postings don't sum to zero.
+func (d *dbClient) CheckLedger(ctx context.Context) (Resp *m.LedgerCheck, err error){
+	err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+		currencies := []m.Imbalance{}
+		entries := []m.Imbalance{}
+		err = tx.SelectContext(ctx, &currencies, SelectUnbalancedCurrencies)
+		if err != nil{
+			return
+		}
+		err = tx.SelectContext(ctx, &entries, SelectUnbalancedEntries)
+		if err != nil{
+			return
+		}
+		Resp = &m.LedgerCheck{Imbalances: append(currencies, entries...)}
+		Resp.Balanced = len(Resp.Imbalances) == 0
+		return
+	})
+	if err != nil{
+		return
+	}
+	if !Resp.Balanced{
+		logging.FromContext(ctx).WithField("imbalances", Resp.Imbalances).Error("ledger is unbalanced")
+	}
+	return
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/fedorkolmykow/avitojob/pkg/metrics"
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+)
+
+func TestEntryCheck(t *testing.T){
+	cases := []struct{
+		Legs     []*ledgerLeg
+		Balanced bool
+	}{
+		{
+			Legs: []*ledgerLeg{
+				userLeg(&m.Transaction{UserId: 1, Change: 10000, Currency: "RUB"}),
+				systemLeg(m.ExternalAccount("Sberbank"), "RUB", -10000),
+			},
+			Balanced: true,
+		},
+		{
+			Legs: []*ledgerLeg{
+				userLeg(&m.Transaction{UserId: 1, Change: -10000, Currency: "RUB"}),
+				userLeg(&m.Transaction{UserId: 2, Change: 125, Currency: "USD"}),
+			},
+			Balanced: false,
+		},
+		{
+			Legs: []*ledgerLeg{
+				userLeg(&m.Transaction{UserId: 1, Change: -10000, Currency: "RUB"}),
+				systemLeg(m.AccountFX, "RUB", 10000),
+				systemLeg(m.AccountFX, "USD", -125),
+				userLeg(&m.Transaction{UserId: 2, Change: 125, Currency: "USD"}),
+			},
+			Balanced: true,
+		},
+		{
+			Legs: []*ledgerLeg{systemLeg(m.AccountFees, "RUB", 1)},
+			Balanced: false,
+		},
+	}
+	for num, c := range cases{
+		entry := &ledgerEntry{Kind: entryChange, Legs: c.Legs}
+		err := entry.check()
+		if (err == nil) != c.Balanced{
+			t.Errorf("[%d] unexpected check result: %v, expected balanced: %v", num, err, c.Balanced)
+		}
+	}
+}
+
+func TestEntryBalance(t *testing.T){
+	entry := &ledgerEntry{Kind: entryRefund, Legs: []*ledgerLeg{
+		userLeg(&m.Transaction{UserId: 1, Change: 5000, Currency: "RUB"}),
+		userLeg(&m.Transaction{UserId: 2, Change: -62, Currency: "USD"}),
+	}}
+	err := entry.balance(map[string]string{"RUB": m.AccountFX, "USD": m.AccountFX})
+	if err != nil{
+		t.Fatal(err)
+	}
+	if len(entry.Legs) != 4{
+		t.Fatalf("unexpected number of legs: %d, expected: 4", len(entry.Legs))
+	}
+	rub, usd := entry.Legs[2], entry.Legs[3]
+	if rub.System != m.AccountFX || rub.Currency != "RUB" || rub.Amount != -5000{
+		t.Errorf("unexpected RUB leg: %+v", rub)
+	}
+	if usd.System != m.AccountFX || usd.Currency != "USD" || usd.Amount != 62{
+		t.Errorf("unexpected USD leg: %+v", usd)
+	}
+	if err = entry.check(); err != nil{
+		t.Error(err)
+	}
+
+	entry = &ledgerEntry{Kind: entryRefund, Legs: []*ledgerLeg{
+		userLeg(&m.Transaction{UserId: 1, Change: -5000, Currency: "RUB"}),
+	}}
+	if err = entry.balance(map[string]string{"USD": m.AccountFX}); err == nil{
+		t.Error("expected an error for a currency without a system account")
+	}
+}
+
+func TestCountPosted(t *testing.T){
+	credited, debited := metrics.Credited.WithLabelValues("USD"), metrics.Debited.WithLabelValues("RUB")
+	creditedBefore, debitedBefore := testutil.ToFloat64(credited), testutil.ToFloat64(debited)
+	transfersBefore := testutil.ToFloat64(metrics.Transfers)
+	countPosted(&ledgerEntry{Kind: entryTransfer, Legs: []*ledgerLeg{
+		userLeg(&m.Transaction{UserId: 1, Change: -10000, Currency: "RUB"}),
+		systemLeg(m.AccountFX, "RUB", 10000),
+		systemLeg(m.AccountFX, "USD", -125),
+		userLeg(&m.Transaction{UserId: 2, Change: 125, Currency: "USD"}),
+	}})
+	countPosted(nil)
+	if got := testutil.ToFloat64(credited) - creditedBefore; got != 125{
+		t.Errorf("unexpected credited USD: %v, expected: 125", got)
+	}
+	if got := testutil.ToFloat64(debited) - debitedBefore; got != 10000{
+		t.Errorf("unexpected debited RUB: %v, expected: 10000", got)
+	}
+	if got := testutil.ToFloat64(metrics.Transfers) - transfersBefore; got != 1{
+		t.Errorf("unexpected number of transfers: %v, expected: 1", got)
+	}
+}
@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/fedorkolmykow/avitojob/pkg/config"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const(
+	CreateMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+                     version integer NOT NULL,
+                     name VARCHAR(255) NOT NULL,
+                     applied_at timestamptz NOT NULL,
+                     CONSTRAINT schema_migrations_pk PRIMARY KEY (version));`
+	LockMigrations = `SELECT pg_advisory_xact_lock($1);`
+	SelectMigrations = `SELECT version, applied_at FROM schema_migrations ORDER BY version;`
+	InsertMigration = `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3);`
+	DeleteMigration = `DELETE FROM schema_migrations WHERE version=$1;`
+)
+
+// migrationsLock is the key of the advisory lock taken by every migration step,
+// so instances starting at once migrate one after another.
+const migrationsLock = 4215001
+
+// Migration is a versioned change of the schema with the script undoing it.
+// Files are named like 0002_idempotency_keys.up.sql and 0002_idempotency_keys.down.sql.
+type Migration struct{
+	Version int
+	Name string
+	Up string
+	Down string
+}
+
+// MigrationStatus tells whether a migration is applied, AppliedAt is zero for pending ones.
+type MigrationStatus struct{
+	Migration
+	AppliedAt time.Time
+}
+
+func (s MigrationStatus) Applied() bool{
+	return !s.AppliedAt.IsZero()
+}
+
+var migrationName = regexp.MustCompile(`^([0-9]+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// loadMigrations reads the migrations of files. Versions start at 1 and go without gaps,
+// every migration has both scripts.
+func loadMigrations(files fs.FS) (migrations []Migration, err error){
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil{
+		return
+	}
+	byVersion := map[int]*Migration{}
+	for _, name := range names{
+		match := migrationName.FindStringSubmatch(name)
+		if match == nil{
+			return nil, errors.New("invalid migration file name " + name)
+		}
+		version, _ := strconv.Atoi(match[1])
+		mg, ok := byVersion[version]
+		if !ok{
+			mg = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mg
+		}
+		if mg.Name != match[2]{
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mg.Name, match[2])
+		}
+		body, err := fs.ReadFile(files, name)
+		if err != nil{
+			return nil, err
+		}
+		if match[3] == "up"{
+			mg.Up = string(body)
+		} else{
+			mg.Down = string(body)
+		}
+	}
+	for version := 1; version <= len(byVersion); version++{
+		mg, ok := byVersion[version]
+		if !ok{
+			return nil, fmt.Errorf("migration %d is missing", version)
+		}
+		if mg.Up == "" || mg.Down == ""{
+			return nil, fmt.Errorf("migration %d needs both up and down scripts", version)
+		}
+		migrations = append(migrations, *mg)
+	}
+	return
+}
+
+// Migrator applies and reverts the migrations embedded into the binary. Applied versions
+// are kept in schema_migrations. Every migration runs in its own transaction together with
+// the change of schema_migrations, so a failed one leaves no trace.
+type Migrator struct{
+	db *sqlx.DB
+	migrations []Migration
+}
+
+// step runs fn in a transaction holding the migrations lock. applied maps
+// the applied versions to the time they were applied at.
+func (mg *Migrator) step(ctx context.Context, fn func(tx *sqlx.Tx, applied map[int]time.Time) error) (err error){
+	tx, err := mg.db.BeginTxx(ctx, nil)
+	if err != nil{
+		return
+	}
+	_, err = tx.ExecContext(ctx, LockMigrations, migrationsLock)
+	if err != nil{
+		return rollAndErr(tx, err)
+	}
+	_, err = tx.ExecContext(ctx, CreateMigrationsTable)
+	if err != nil{
+		return rollAndErr(tx, err)
+	}
+	rows := []struct{
+		Version int `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}{}
+	err = tx.SelectContext(ctx, &rows, SelectMigrations)
+	if err != nil{
+		return rollAndErr(tx, err)
+	}
+	applied := map[int]time.Time{}
+	for _, row := range rows{
+		applied[row.Version] = row.AppliedAt
+	}
+	err = fn(tx, applied)
+	if err != nil{
+		return rollAndErr(tx, err)
+	}
+	return tx.Commit()
+}
+
+// Up applies all pending migrations in the order of versions.
+func (mg *Migrator) Up(ctx context.Context) (done []Migration, err error){
+	for{
+		var next *Migration
+		err = mg.step(ctx, func(tx *sqlx.Tx, applied map[int]time.Time) (err error){
+			for i := range mg.migrations{
+				if _, ok := applied[mg.migrations[i].Version]; !ok{
+					next = &mg.migrations[i]
+					break
+				}
+			}
+			if next == nil{
+				return
+			}
+			_, err = tx.ExecContext(ctx, next.Up)
+			if err != nil{
+				return fmt.Errorf("apply migration %04d_%s: %w", next.Version, next.Name, err)
+			}
+			_, err = tx.ExecContext(ctx, InsertMigration, next.Version, next.Name, time.Now())
+			return
+		})
+		if err != nil || next == nil{
+			return
+		}
+		log.Info("applied migration " + fmt.Sprintf("%04d_%s", next.Version, next.Name))
+		done = append(done, *next)
+	}
+}
+
+// Down reverts the last steps applied migrations.
+func (mg *Migrator) Down(ctx context.Context, steps int) (done []Migration, err error){
+	for len(done) < steps{
+		var last *Migration
+		err = mg.step(ctx, func(tx *sqlx.Tx, applied map[int]time.Time) (err error){
+			for i := len(mg.migrations) - 1; i >= 0; i--{
+				if _, ok := applied[mg.migrations[i].Version]; ok{
+					last = &mg.migrations[i]
+					break
+				}
+			}
+			if last == nil{
+				return
+			}
+			_, err = tx.ExecContext(ctx, last.Down)
+			if err != nil{
+				return fmt.Errorf("revert migration %04d_%s: %w", last.Version, last.Name, err)
+			}
+			_, err = tx.ExecContext(ctx, DeleteMigration, last.Version)
+			return
+		})
+		if err != nil || last == nil{
+			return
+		}
+		log.Info("reverted migration " + fmt.Sprintf("%04d_%s", last.Version, last.Name))
+		done = append(done, *last)
+	}
+	return
+}
+
+// Status lists all migrations known to the binary.
+func (mg *Migrator) Status(ctx context.Context) (status []MigrationStatus, err error){
+	err = mg.step(ctx, func(tx *sqlx.Tx, applied map[int]time.Time) error{
+		status = make([]MigrationStatus, 0, len(mg.migrations))
+		for _, migration := range mg.migrations{
+			status = append(status, MigrationStatus{Migration: migration, AppliedAt: applied[migration.Version]})
+		}
+		return nil
+	})
+	return
+}
+
+// Force marks the migrations up to version as applied and the later ones as pending without running
+// any scripts. It adopts databases whose schema was created before schema_migrations existed.
+func (mg *Migrator) Force(ctx context.Context, version int) (err error){
+	if version < 0 || version > len(mg.migrations){
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return mg.step(ctx, func(tx *sqlx.Tx, applied map[int]time.Time) (err error){
+		for _, migration := range mg.migrations{
+			_, ok := applied[migration.Version]
+			switch{
+			case migration.Version <= version && !ok:
+				_, err = tx.ExecContext(ctx, InsertMigration, migration.Version, migration.Name, time.Now())
+			case migration.Version > version && ok:
+				_, err = tx.ExecContext(ctx, DeleteMigration, migration.Version)
+			}
+			if err != nil{
+				return
+			}
+		}
+		return
+	})
+}
+
+func (mg *Migrator) Close() error{
+	return mg.db.Close()
+}
+
+func NewMigrator(cfg config.Postgres) (*Migrator, error){
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil{
+		return nil, err
+	}
+	migrations, err := loadMigrations(files)
+	if err != nil{
+		return nil, err
+	}
+	db, err := connect(cfg.URL)
+	if err != nil{
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
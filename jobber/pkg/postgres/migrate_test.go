@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T){
+	file := func(body string) *fstest.MapFile{
+		return &fstest.MapFile{Data: []byte(body)}
+	}
+	cases := []struct{
+		Files    fstest.MapFS
+		Versions int
+		Err      string
+	}{
+		{
+			Files: fstest.MapFS{
+				"0002_holds.down.sql": file("DROP TABLE Holds;"),
+				"0001_init.up.sql": file("CREATE TABLE Users ();"),
+				"0002_holds.up.sql": file("CREATE TABLE Holds ();"),
+				"0001_init.down.sql": file("DROP TABLE Users;"),
+			},
+			Versions: 2,
+		},
+		{
+			Files: fstest.MapFS{
+				"0001_init.up.sql": file("CREATE TABLE Users ();"),
+			},
+			Err: "needs both",
+		},
+		{
+			Files: fstest.MapFS{
+				"0001_init.up.sql": file("CREATE TABLE Users ();"),
+				"0001_init.down.sql": file("DROP TABLE Users;"),
+				"0003_holds.up.sql": file("CREATE TABLE Holds ();"),
+				"0003_holds.down.sql": file("DROP TABLE Holds;"),
+			},
+			Err: "migration 2 is missing",
+		},
+		{
+			Files: fstest.MapFS{
+				"0001_init.up.sql": file("CREATE TABLE Users ();"),
+				"0001_users.down.sql": file("DROP TABLE Users;"),
+			},
+			Err: "two names",
+		},
+		{
+			Files: fstest.MapFS{
+				"init.sql": file("CREATE TABLE Users ();"),
+			},
+			Err: "invalid migration file name",
+		},
+	}
+	for num, c := range cases{
+		migrations, err := loadMigrations(c.Files)
+		if c.Err != ""{
+			if err == nil || !strings.Contains(err.Error(), c.Err){
+				t.Errorf("[%d] unexpected error: %v, expected: %s", num, err, c.Err)
+			}
+			continue
+		}
+		if err != nil{
+			t.Errorf("[%d] unexpected error: %v", num, err)
+			continue
+		}
+		if len(migrations) != c.Versions{
+			t.Errorf("[%d] unexpected number of migrations: %d, expected: %d", num, len(migrations), c.Versions)
+			continue
+		}
+		for i, mg := range migrations{
+			if mg.Version != i + 1{
+				t.Errorf("[%d] migrations are out of order: %+v", num, migrations)
+			}
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T){
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil{
+		t.Fatal(err)
+	}
+	migrations, err := loadMigrations(files)
+	if err != nil{
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 || migrations[0].Name != "init"{
+		t.Errorf("the first migration isn't the initial schema: %+v", migrations)
+	}
+}
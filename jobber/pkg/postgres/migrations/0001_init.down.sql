@@ -0,0 +1,2 @@
+DROP TABLE Transactions;
+DROP TABLE Users;
@@ -0,0 +1,8 @@
+CREATE TABLE IdempotencyKeys (
+	idem_key VARCHAR(255) NOT NULL,
+	request_hash VARCHAR(64) NOT NULL,
+	response TEXT NOT NULL,
+	CONSTRAINT IdempotencyKeys_pk PRIMARY KEY (idem_key)
+) WITH (
+  OIDS=FALSE
+);
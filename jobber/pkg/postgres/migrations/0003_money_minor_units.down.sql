@@ -0,0 +1,3 @@
+ALTER TABLE Users ALTER COLUMN balance TYPE double precision USING balance / 100.0;
+ALTER TABLE Transactions ALTER COLUMN init_balance TYPE double precision USING init_balance / 100.0;
+ALTER TABLE Transactions ALTER COLUMN change TYPE double precision USING change / 100.0;
@@ -0,0 +1,4 @@
+-- Money is stored in kopecks instead of double precision rubles.
+ALTER TABLE Users ALTER COLUMN balance TYPE bigint USING round(balance * 100);
+ALTER TABLE Transactions ALTER COLUMN init_balance TYPE bigint USING round(init_balance * 100);
+ALTER TABLE Transactions ALTER COLUMN change TYPE bigint USING round(change * 100);
@@ -0,0 +1,2 @@
+DROP INDEX Transactions_user_trans_idx;
+DROP INDEX Transactions_user_change_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX Transactions_user_trans_idx ON Transactions (user_id, trans_id);
+CREATE INDEX Transactions_user_change_idx ON Transactions (user_id, change, trans_id);
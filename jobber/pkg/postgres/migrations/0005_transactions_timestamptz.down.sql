@@ -0,0 +1,3 @@
+DROP INDEX Transactions_user_time_idx;
+ALTER TABLE Transactions ALTER COLUMN time TYPE VARCHAR(255)
+	USING to_char(time AT TIME ZONE 'UTC', 'DD Mon YY HH24:MI') || ' UTC';
@@ -0,0 +1,4 @@
+-- time was stored as RFC822 text, e.g. "17 Nov 09 20:34 UTC".
+ALTER TABLE Transactions ALTER COLUMN time TYPE timestamptz
+	USING to_timestamp(left(time, 15), 'DD Mon YY HH24:MI')::timestamp AT TIME ZONE substr(time, 17);
+CREATE INDEX Transactions_user_time_idx ON Transactions (user_id, time, trans_id);
@@ -0,0 +1,2 @@
+DROP TABLE Holds;
+ALTER TABLE Users DROP COLUMN held;
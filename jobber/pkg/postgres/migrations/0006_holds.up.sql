@@ -0,0 +1,15 @@
+ALTER TABLE Users ADD COLUMN held bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE Holds (
+	hold_id serial NOT NULL,
+	user_id integer NOT NULL,
+	amount bigint NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	comment VARCHAR(255) NOT NULL,
+	time timestamptz NOT NULL,
+	CONSTRAINT Holds_pk PRIMARY KEY (hold_id)
+) WITH (
+  OIDS=FALSE
+);
+
+ALTER TABLE Holds ADD CONSTRAINT Holds_fk0 FOREIGN KEY (user_id) REFERENCES Users(user_id);
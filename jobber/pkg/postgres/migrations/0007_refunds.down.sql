@@ -0,0 +1,6 @@
+DROP SEQUENCE transfer_id_seq;
+DROP INDEX Transactions_refund_of_idx;
+DROP INDEX Transactions_transfer_idx;
+ALTER TABLE Transactions DROP CONSTRAINT Transactions_fk1;
+ALTER TABLE Transactions DROP COLUMN refund_of;
+ALTER TABLE Transactions DROP COLUMN transfer_id;
@@ -0,0 +1,7 @@
+ALTER TABLE Transactions ADD COLUMN refund_of integer;
+ALTER TABLE Transactions ADD COLUMN transfer_id integer;
+ALTER TABLE Transactions ADD CONSTRAINT Transactions_fk1 FOREIGN KEY (refund_of) REFERENCES Transactions(trans_id);
+CREATE INDEX Transactions_refund_of_idx ON Transactions (refund_of);
+CREATE INDEX Transactions_transfer_idx ON Transactions (transfer_id);
+
+CREATE SEQUENCE transfer_id_seq;
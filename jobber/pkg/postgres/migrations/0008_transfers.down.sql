@@ -0,0 +1,4 @@
+-- The sequence was created by 0007, it must outlive the table.
+ALTER SEQUENCE transfer_id_seq OWNED BY NONE;
+ALTER TABLE Transactions DROP CONSTRAINT Transactions_fk2;
+DROP TABLE Transfers;
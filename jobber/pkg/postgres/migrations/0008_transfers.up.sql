@@ -0,0 +1,37 @@
+CREATE TABLE Transfers (
+	transfer_id integer NOT NULL DEFAULT nextval('transfer_id_seq'),
+	source_id integer NOT NULL,
+	target_id integer NOT NULL,
+	amount bigint NOT NULL,
+	comment VARCHAR(255) NOT NULL,
+	time timestamptz NOT NULL,
+	CONSTRAINT Transfers_pk PRIMARY KEY (transfer_id)
+) WITH (
+  OIDS=FALSE
+);
+
+ALTER SEQUENCE transfer_id_seq OWNED BY Transfers.transfer_id;
+
+-- Transfers made before 0007 have no transfer_id. Their legs share source, time and comment
+-- and have opposite changes, the target leg is the nearest one written after the source leg.
+WITH pairs AS (
+	SELECT DISTINCT ON (s.trans_id) s.trans_id AS source_trans, t.trans_id AS target_trans
+	FROM Transactions s JOIN Transactions t
+		ON t.source = s.source AND t.user_id <> s.user_id AND t.change = -s.change
+		AND t.comment = s.comment AND t.time = s.time AND t.trans_id > s.trans_id
+	WHERE s.transfer_id IS NULL AND t.transfer_id IS NULL
+		AND s.refund_of IS NULL AND t.refund_of IS NULL
+		AND s.source ~ '^[0-9]+$' AND s.source::integer = s.user_id AND s.change < 0
+	ORDER BY s.trans_id, t.trans_id
+), ids AS (
+	SELECT source_trans, target_trans, nextval('transfer_id_seq') AS transfer_id FROM pairs
+)
+UPDATE Transactions SET transfer_id = ids.transfer_id FROM ids
+WHERE Transactions.trans_id IN (ids.source_trans, ids.target_trans);
+
+INSERT INTO Transfers (transfer_id, source_id, target_id, amount, comment, time)
+SELECT s.transfer_id, s.user_id, t.user_id, t.change, s.comment, s.time
+FROM Transactions s JOIN Transactions t ON t.transfer_id = s.transfer_id AND t.trans_id <> s.trans_id
+WHERE s.change < 0 AND s.refund_of IS NULL AND t.refund_of IS NULL;
+
+ALTER TABLE Transactions ADD CONSTRAINT Transactions_fk2 FOREIGN KEY (transfer_id) REFERENCES Transfers(transfer_id);
@@ -0,0 +1,20 @@
+-- Balances return to Users from the ruble wallets. Wallets in other currencies are lost,
+-- their transactions stay.
+ALTER TABLE Users ADD COLUMN balance bigint NOT NULL DEFAULT 0;
+ALTER TABLE Users ADD COLUMN held bigint NOT NULL DEFAULT 0;
+UPDATE Users u SET balance = w.balance, held = w.held FROM Wallets w WHERE w.user_id = u.user_id AND w.currency = 'RUB';
+ALTER TABLE Users ALTER COLUMN balance DROP DEFAULT;
+
+DROP TABLE Wallets;
+
+ALTER TABLE Transactions DROP COLUMN currency;
+ALTER TABLE Transactions DROP COLUMN rate;
+ALTER TABLE Transactions DROP COLUMN counter_change;
+ALTER TABLE Transactions DROP COLUMN counter_currency;
+
+ALTER TABLE Holds DROP COLUMN currency;
+
+ALTER TABLE Transfers DROP COLUMN currency;
+ALTER TABLE Transfers DROP COLUMN target_amount;
+ALTER TABLE Transfers DROP COLUMN target_currency;
+ALTER TABLE Transfers DROP COLUMN rate;
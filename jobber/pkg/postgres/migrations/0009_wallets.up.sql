@@ -0,0 +1,35 @@
+-- Balances move from Users to per currency Wallets. Existing balances are rubles.
+CREATE TABLE Wallets (
+	user_id integer NOT NULL,
+	currency VARCHAR(3) NOT NULL,
+	balance bigint NOT NULL,
+	held bigint NOT NULL DEFAULT 0,
+	CONSTRAINT Wallets_pk PRIMARY KEY (user_id, currency)
+) WITH (
+  OIDS=FALSE
+);
+
+ALTER TABLE Wallets ADD CONSTRAINT Wallets_fk0 FOREIGN KEY (user_id) REFERENCES Users(user_id);
+
+INSERT INTO Wallets (user_id, currency, balance, held) SELECT user_id, 'RUB', balance, held FROM Users;
+
+ALTER TABLE Users DROP COLUMN balance;
+ALTER TABLE Users DROP COLUMN held;
+
+ALTER TABLE Transactions ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE Transactions ADD COLUMN rate NUMERIC;
+ALTER TABLE Transactions ADD COLUMN counter_change bigint;
+ALTER TABLE Transactions ADD COLUMN counter_currency VARCHAR(3);
+
+UPDATE Transactions t SET rate = 1, counter_change = o.change, counter_currency = 'RUB'
+FROM Transactions o
+WHERE t.transfer_id IS NOT NULL AND o.transfer_id = t.transfer_id AND o.trans_id <> t.trans_id;
+
+ALTER TABLE Holds ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+
+ALTER TABLE Transfers ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE Transfers ADD COLUMN target_amount bigint;
+ALTER TABLE Transfers ADD COLUMN target_currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE Transfers ADD COLUMN rate NUMERIC NOT NULL DEFAULT 1;
+UPDATE Transfers SET target_amount = amount;
+ALTER TABLE Transfers ALTER COLUMN target_amount SET NOT NULL;
@@ -0,0 +1,5 @@
+DROP TRIGGER Postings_balanced ON Postings;
+DROP FUNCTION check_entry_balanced();
+DROP TABLE Postings;
+DROP TABLE LedgerEntries;
+DROP TABLE Accounts;
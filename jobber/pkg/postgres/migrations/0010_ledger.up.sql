@@ -0,0 +1,75 @@
+-- Double-entry ledger. Every change of a wallet is booked against a system account, so the postings
+-- of all accounts sum to zero. Balances which existed before the ledger are booked in one opening entry
+-- against the opening account of their currency.
+
+CREATE TABLE Accounts (
+	account_id serial NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	user_id integer,
+	currency VARCHAR(3) NOT NULL,
+	CONSTRAINT Accounts_pk PRIMARY KEY (account_id),
+	CONSTRAINT Accounts_name_currency_key UNIQUE (name, currency)
+) WITH (
+  OIDS=FALSE
+);
+
+ALTER TABLE Accounts ADD CONSTRAINT Accounts_fk0 FOREIGN KEY (user_id) REFERENCES Users(user_id);
+
+CREATE TABLE LedgerEntries (
+	entry_id serial NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	time timestamptz NOT NULL,
+	CONSTRAINT LedgerEntries_pk PRIMARY KEY (entry_id)
+) WITH (
+  OIDS=FALSE
+);
+
+CREATE TABLE Postings (
+	posting_id serial NOT NULL,
+	entry_id integer NOT NULL,
+	account_id integer NOT NULL,
+	amount bigint NOT NULL,
+	trans_id integer,
+	CONSTRAINT Postings_pk PRIMARY KEY (posting_id)
+) WITH (
+  OIDS=FALSE
+);
+
+ALTER TABLE Postings ADD CONSTRAINT Postings_fk0 FOREIGN KEY (entry_id) REFERENCES LedgerEntries(entry_id);
+ALTER TABLE Postings ADD CONSTRAINT Postings_fk1 FOREIGN KEY (account_id) REFERENCES Accounts(account_id);
+ALTER TABLE Postings ADD CONSTRAINT Postings_fk2 FOREIGN KEY (trans_id) REFERENCES Transactions(trans_id);
+
+CREATE INDEX Postings_entry_idx ON Postings (entry_id);
+CREATE INDEX Postings_account_idx ON Postings (account_id);
+CREATE INDEX Postings_trans_idx ON Postings (trans_id);
+
+-- Postings of an entry must sum to zero in every currency. The check is deferred
+-- to the commit, when all postings of the entry are written.
+CREATE FUNCTION check_entry_balanced() RETURNS trigger AS $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM Postings p JOIN Accounts a ON a.account_id = p.account_id
+		WHERE p.entry_id = NEW.entry_id GROUP BY a.currency HAVING SUM(p.amount) <> 0) THEN
+		RAISE EXCEPTION 'ledger entry % is unbalanced', NEW.entry_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER Postings_balanced AFTER INSERT OR UPDATE ON Postings
+	DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE PROCEDURE check_entry_balanced();
+
+INSERT INTO Accounts (name, user_id, currency) SELECT 'user:' || user_id, user_id, currency FROM Wallets;
+INSERT INTO Accounts (name, currency) SELECT DISTINCT 'opening', currency FROM Wallets;
+
+INSERT INTO LedgerEntries (kind, time) SELECT 'opening', now() WHERE EXISTS (SELECT 1 FROM Wallets);
+
+INSERT INTO Postings (entry_id, account_id, amount)
+SELECT e.entry_id, a.account_id, w.balance
+FROM Wallets w JOIN Accounts a ON a.user_id = w.user_id AND a.currency = w.currency, LedgerEntries e
+WHERE e.kind = 'opening'
+UNION ALL
+SELECT e.entry_id, a.account_id, -s.total
+FROM (SELECT currency, SUM(balance) AS total FROM Wallets GROUP BY currency) s
+JOIN Accounts a ON a.name = 'opening' AND a.currency = s.currency, LedgerEntries e
+WHERE e.kind = 'opening';
+
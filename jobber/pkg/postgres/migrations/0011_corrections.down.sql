@@ -0,0 +1,2 @@
+ALTER TABLE Transactions DROP CONSTRAINT Transactions_fk3;
+ALTER TABLE Transactions DROP COLUMN corrects;
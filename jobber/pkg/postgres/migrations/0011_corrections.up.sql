@@ -0,0 +1,3 @@
+-- Corrective transactions of reconciliation point to the transaction before which they fill a gap in the history.
+ALTER TABLE Transactions ADD COLUMN corrects integer;
+ALTER TABLE Transactions ADD CONSTRAINT Transactions_fk3 FOREIGN KEY (corrects) REFERENCES Transactions(trans_id);
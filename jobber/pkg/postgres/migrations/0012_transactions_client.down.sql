@@ -0,0 +1 @@
+ALTER TABLE Transactions DROP COLUMN client_id;
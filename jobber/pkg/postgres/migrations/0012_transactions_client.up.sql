@@ -0,0 +1,4 @@
+-- Every transaction is attributed to the client which made it. Transactions made before
+-- authentication have the client 'unknown', new ones must name theirs.
+ALTER TABLE Transactions ADD COLUMN client_id text NOT NULL DEFAULT 'unknown';
+ALTER TABLE Transactions ALTER COLUMN client_id DROP DEFAULT;
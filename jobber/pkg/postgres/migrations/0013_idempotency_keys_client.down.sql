@@ -0,0 +1,5 @@
+-- keys used by several clients keep the response of one of them
+DELETE FROM IdempotencyKeys a USING IdempotencyKeys b WHERE a.idem_key = b.idem_key AND a.client_id > b.client_id;
+ALTER TABLE IdempotencyKeys DROP CONSTRAINT IdempotencyKeys_pk;
+ALTER TABLE IdempotencyKeys DROP COLUMN client_id;
+ALTER TABLE IdempotencyKeys ADD CONSTRAINT IdempotencyKeys_pk PRIMARY KEY (idem_key);
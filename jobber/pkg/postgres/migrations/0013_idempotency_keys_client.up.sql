@@ -0,0 +1,6 @@
+-- Idempotency keys are chosen by clients, so every client has its own keys. Keys saved before
+-- authentication belong to the client 'unknown'.
+ALTER TABLE IdempotencyKeys ADD COLUMN client_id text NOT NULL DEFAULT 'unknown';
+ALTER TABLE IdempotencyKeys ALTER COLUMN client_id DROP DEFAULT;
+ALTER TABLE IdempotencyKeys DROP CONSTRAINT IdempotencyKeys_pk;
+ALTER TABLE IdempotencyKeys ADD CONSTRAINT IdempotencyKeys_pk PRIMARY KEY (client_id, idem_key);
@@ -1,260 +1,820 @@
 package postgres
 
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/fedorkolmykow/avitojob/pkg/auth"
+	"github.com/fedorkolmykow/avitojob/pkg/config"
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
 	m "github.com/fedorkolmykow/avitojob/pkg/models"
-	"os"
+	"net"
 	"strconv"
 	"time"
 
 	_ "github.com/jackc/pgx/stdlib"
 	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	log "github.com/sirupsen/logrus"
 )
 
 const(
-	CheckExistence = `SELECT EXISTS(SELECT user_id FROM Users WHERE user_id=$1) ;`
-	SelectUserBalance = `SELECT balance FROM Users WHERE user_id=$1;`
-	InsertUser = `INSERT INTO Users (user_id, balance) VALUES ($1, $2) RETURNING user_id;`
-	UpdateUserBalance = `UPDATE Users SET balance = balance + $1 WHERE user_id = $2 RETURNING balance;`
+	CheckExistence = `SELECT EXISTS(SELECT user_id FROM Wallets WHERE user_id=$1 AND currency=$2) ;`
+	SelectUserBalance = `SELECT balance, held FROM Wallets WHERE user_id=$1 AND currency=$2;`
+	InsertUser = `INSERT INTO Users (user_id) VALUES ($1) ON CONFLICT DO NOTHING;`
+	InsertWallet = `INSERT INTO Wallets (user_id, currency, balance, held) VALUES ($1, $2, $3, 0);`
+	UpdateUserBalance = `UPDATE Wallets SET balance = balance + $1 WHERE user_id = $2 AND currency = $3 RETURNING balance;`
+	UpdateUserHeld = `UPDATE Wallets SET held = held + $1 WHERE user_id = $2 AND currency = $3 RETURNING balance, held;`
+	InsertHold = `INSERT INTO Holds (user_id, amount, currency, status, comment, time) VALUES ($1, $2, $3, $4, $5, $6) RETURNING hold_id;`
+	SelectHold = `SELECT * FROM Holds WHERE hold_id=$1 AND user_id=$2 FOR UPDATE;`
+	UpdateHoldStatus = `UPDATE Holds SET status = $1 WHERE hold_id = $2;`
 	SetIsolationSerializable = `SET TRANSACTION ISOLATION LEVEL SERIALIZABLE;`
-	InsertTrans = `INSERT INTO Transactions (user_id, init_balance, change, time, comment, source)  
-                     VALUES (:user_id, :init_balance, :change, :time, :comment, :source);`
-	SelectTransactions = `SELECT * FROM Transactions WHERE user_id=$1;`
+	InsertTrans = `INSERT INTO Transactions (user_id, init_balance, change, currency, time, comment, source, refund_of, transfer_id,
+                     rate, counter_change, counter_currency, corrects, client_id)  
+                     VALUES (:user_id, :init_balance, :change, :currency, :time, :comment, :source, :refund_of, :transfer_id,
+                     :rate, :counter_change, :counter_currency, :corrects, :client_id) RETURNING trans_id;`
+	InsertTransfer = `INSERT INTO Transfers (source_id, target_id, amount, currency, target_amount, target_currency, rate, comment, time)
+                     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING transfer_id;`
+	SelectTransfer = `SELECT * FROM Transfers WHERE transfer_id=$1;`
+	SelectTransferTransactions = `SELECT * FROM Transactions WHERE transfer_id=$1 ORDER BY trans_id;`
+	SelectTransferRefunds = `SELECT * FROM Transactions WHERE refund_of IN (SELECT trans_id FROM Transactions WHERE transfer_id=$1) ORDER BY trans_id;`
+	SelectTransaction = `SELECT * FROM Transactions WHERE trans_id=$1 AND user_id=$2 FOR UPDATE;`
+	SelectTransferLegs = `SELECT * FROM Transactions WHERE transfer_id=$1 AND refund_of IS NULL ORDER BY trans_id FOR UPDATE;`
+	SelectRefunded = `SELECT COALESCE(SUM(change), 0)::bigint FROM Transactions WHERE refund_of=$1;`
+	SelectTransactions = `SELECT * FROM Transactions WHERE user_id=$1`
+	SelectBalancesAsOf = `SELECT w.user_id, (t.init_balance + t.change)::bigint AS balance FROM Wallets w
+                     CROSS JOIN LATERAL (SELECT t.init_balance, t.change FROM Transactions t
+                     WHERE t.user_id = w.user_id AND t.currency = w.currency AND t.time < ? AND t.corrects IS NULL
+                     ORDER BY t.time DESC, t.trans_id DESC LIMIT 1) t
+                     WHERE w.user_id IN (?) AND w.currency = ? ORDER BY w.user_id;`
+	TimeFrom = ` AND time >= $%d`
+	TimeTo = ` AND time < $%d`
+	AfterChange = ` AND (change, trans_id) > ($%d, $%d)`
+	AfterTime = ` AND (time, trans_id) > ($%d, $%d)`
+	AfterTransId = ` AND trans_id > $%d`
+	OrderByChange = ` ORDER BY change, trans_id`
+	OrderByTime = ` ORDER BY time, trans_id`
+	OrderByTransId = ` ORDER BY trans_id`
+	LimitOffset = ` LIMIT $%d OFFSET $%d;`
+	SelectIdempotencyKey = `SELECT request_hash, response FROM IdempotencyKeys WHERE client_id=$1 AND idem_key=$2;`
+	InsertIdempotencyKey = `INSERT INTO IdempotencyKeys (client_id, idem_key, request_hash, response) VALUES ($1, $2, $3, $4);`
 )
 
 type DbClient interface{
-	UpdateBalance(Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error)
-	UpdateBalances(Req *m.TransferReq) (Resp *m.TransferResp, err error)
-	SelectBalance(Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error)
-	SelectTransactions(Req *m.GetTransactionsReq) (Resp *m.Transactions, err error)
+	UpdateBalance(ctx context.Context, Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error)
+	UpdateBalances(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error)
+	ReplayTransfer(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error)
+	ReserveBalance(ctx context.Context, Req *m.ReserveReq) (Resp *m.HoldResp, err error)
+	CaptureHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error)
+	ReleaseHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error)
+	RefundTransaction(ctx context.Context, Req *m.RefundReq) (Resp *m.RefundResp, err error)
+	SelectBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error)
+	SelectBalances(ctx context.Context, Req *m.GetBalancesReq) (Resp *m.GetBalancesResp, err error)
+	SelectTransfer(ctx context.Context, Req *m.GetTransferReq) (Resp *m.Transfer, err error)
+	SelectTransactions(ctx context.Context, Req *m.GetTransactionsReq) (Resp *m.Transactions, err error)
+	CheckLedger(ctx context.Context) (Resp *m.LedgerCheck, err error)
+	Reconcile(ctx context.Context, repair bool) (Resp *m.ReconciliationReport, err error)
+	RetryStats() RetryStats
+	Ping(ctx context.Context) error
 	Shutdown() error
 }
 
 type dbClient struct{
     db *sqlx.DB
+    maxRetries int
+    stats RetryStats
+    pool prometheus.Collector
 }
 
-func insertTransaction(tx *sqlx.Tx, trans *m.Transaction) error {
-	trans.ChangeTime = time.Now().Format(time.RFC822)
-	_, err := tx.NamedExec(InsertTrans, &trans)
-	log.Trace("inserted transaction with data: " + fmt.Sprintf("%#v", trans))
+// clientID is the client of the request of ctx, m.ClientSystem outside of requests.
+func clientID(ctx context.Context) string{
+	if client, ok := auth.FromContext(ctx); ok{
+		return client.ID
+	}
+	return m.ClientSystem
+}
+
+// insertTransaction writes trans, attributed to the client of the request of ctx. Transactions made
+// outside of requests belong to m.ClientSystem.
+func insertTransaction(ctx context.Context, tx *sqlx.Tx, trans *m.Transaction) error {
+	trans.ChangeTime = time.Now()
+	trans.ClientId = clientID(ctx)
+	query, args, err := tx.BindNamed(InsertTrans, trans)
+	if err != nil{
+		return err
+	}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&trans.TransId)
+	logging.FromContext(ctx).WithFields(log.Fields{"user_id": trans.UserId, "amount": trans.Change, "currency": trans.Currency,
+		"source": trans.Source, "client_id": trans.ClientId}).
+		Trace("inserted transaction")
 	return err
 }
 
-func rollAndErr(tx *sqlx.Tx, err error) error{
-	log.Trace("Rollback")
-	errRoll := tx.Rollback()
-	if errRoll != nil{
-		return errRoll
+func requestHash(req json.Marshaler) (hash string, err error){
+	body, err := req.MarshalJSON()
+	if err != nil{
+		return
+	}
+	sum := sha256.Sum256(body)
+	hash = hex.EncodeToString(sum[:])
+	return
+}
+
+// replayResponse fills resp with the response stored for key of the client of ctx. It fails with
+// ErrIdempotencyConflict when the key was used for a request with another hash.
+func replayResponse(ctx context.Context, tx *sqlx.Tx, key string, hash string, resp json.Unmarshaler) (replayed bool, err error){
+	var storedHash, stored string
+	if key == ""{
+		return
 	}
+	err = tx.QueryRowContext(ctx, SelectIdempotencyKey, clientID(ctx), key).Scan(&storedHash, &stored)
+	if err == sql.ErrNoRows{
+		err = nil
+		return
+	}
+	if err != nil{
+		return
+	}
+	if storedHash != hash{
+		err = m.ErrIdempotencyConflict
+		return
+	}
+	err = resp.UnmarshalJSON([]byte(stored))
+	if err != nil{
+		return
+	}
+	logging.FromContext(ctx).WithField("idempotency_key", key).Trace("replayed response")
+	replayed = true
+	return
+}
+
+// saveResponse stores resp under key of the client of ctx, keys of other clients don't clash with it.
+func saveResponse(ctx context.Context, tx *sqlx.Tx, key string, hash string, resp json.Marshaler) error{
+	if key == ""{
+		return nil
+	}
+	body, err := resp.MarshalJSON()
+	if err != nil{
+		return err
+	}
+	_, err = tx.ExecContext(ctx, InsertIdempotencyKey, clientID(ctx), key, hash, string(body))
 	return err
 }
 
-func createNewUser(tx *sqlx.Tx, tr *m.Transaction) (exists bool, err error){
-	err = tx.QueryRow(CheckExistence, tr.UserId).Scan(&exists)
+// createWallet opens the tr.Currency wallet of the user with tr as its first transaction.
+// The user is created too when it is his first wallet.
+func createWallet(ctx context.Context, tx *sqlx.Tx, tr *m.Transaction) (exists bool, err error){
+	err = tx.QueryRowContext(ctx, CheckExistence, tr.UserId, tr.Currency).Scan(&exists)
 	if err != nil{
 		return
 	}
 	if !exists{
 		if tr.Change < 0{
-			err = errors.New("negative initial balance")
+			err = m.ErrInsufficientFunds
+			return
+		}
+		_, err = tx.ExecContext(ctx, InsertUser, tr.UserId)
+		if err != nil{
 			return
 		}
-		_, err = tx.Exec(InsertUser, tr.UserId ,tr.Change)
+		_, err = tx.ExecContext(ctx, InsertWallet, tr.UserId, tr.Currency, tr.Change)
 		if err != nil{
 			return
 		}
-		log.Trace("Created new user")
-		err = insertTransaction(tx, tr)
+		logging.FromContext(ctx).WithFields(log.Fields{"user_id": tr.UserId, "currency": tr.Currency}).Trace("created new wallet")
+		err = insertTransaction(ctx, tx, tr)
 		return
 	}
 	return
 }
 
-func changeBalance(tx *sqlx.Tx, tr *m.Transaction) (balance float64 ,err error){
-	if tr.InitialBalance + tr.Change < 0{
-		err = errors.New("negative balance")
+// changeBalance applies tr to the user balance. Money held by reservations can't be spent.
+func changeBalance(ctx context.Context, tx *sqlx.Tx, tr *m.Transaction, held m.Money) (balance m.Money ,err error){
+	if tr.InitialBalance - held + tr.Change < 0{
+		err = m.ErrInsufficientFunds
 		return
 	}
-	err = tx.QueryRow(UpdateUserBalance, tr.Change, tr.UserId).Scan(&balance)
+	err = tx.QueryRowContext(ctx, UpdateUserBalance, tr.Change, tr.UserId, tr.Currency).Scan(&balance)
 	if err != nil{
 		return
 	}
-	err = insertTransaction(tx, tr)
+	err = insertTransaction(ctx, tx, tr)
 	if err != nil{
 		return
 	}
 	return
 }
 
-func (d *dbClient) UpdateBalance(Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error){
-	trans := &m.Transaction{
-		Change: Req.Change,
-		UserId: Req.UserId,
-		Comment: Req.Comment,
-		Source: Req.Source,
-	}
-	Resp = &m.ChangeBalanceResp{
-		UserId: Req.UserId,
-		Balance: Req.Change,
-	}
-	tx, err := d.db.Beginx()
-	if err != nil{
-		return
-	}
-	_, err = tx.Exec(SetIsolationSerializable)
+func (d *dbClient) UpdateBalance(ctx context.Context, Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error){
+	hash, err := requestHash(Req)
 	if err != nil{
 		return
 	}
-	log.Trace("start transaction with data: " + fmt.Sprintf("%#v", Req))
-	existed, err := createNewUser(tx, trans)
-	if err != nil{
-		err = rollAndErr(tx, err)
-		return
+	account := Req.Account
+	if account == "" || account == m.AccountExternal{
+		account = m.ExternalAccount(Req.Source)
 	}
-	if existed{
-		err = tx.QueryRow(SelectUserBalance, Req.UserId).Scan(&trans.InitialBalance)
-		if err != nil{
-			err = rollAndErr(tx, err)
+	var posted *ledgerEntry
+	err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+		posted = nil
+		user := userLeg(&m.Transaction{
+			Change: Req.Change,
+			Currency: Req.Currency,
+			UserId: Req.UserId,
+			Comment: Req.Comment,
+			Source: Req.Source,
+		})
+		user.OpenWallet = true
+		Resp = &m.ChangeBalanceResp{
+			UserId: Req.UserId,
+			Currency: Req.Currency,
+		}
+		logging.FromContext(ctx).WithFields(Req.LogFields()).Trace("start transaction")
+		replayed, err := replayResponse(ctx, tx, Req.IdempotencyKey, hash, Resp)
+		if err != nil || replayed{
 			return
 		}
-		Resp.Balance, err = changeBalance(tx, trans)
+		entry := &ledgerEntry{
+			Kind: entryChange,
+			Legs: []*ledgerLeg{user, systemLeg(account, Req.Currency, -Req.Change)},
+		}
+		err = post(ctx, tx, entry)
 		if err != nil{
-			err = rollAndErr(tx, err)
 			return
 		}
-	}
-	err = tx.Commit()
+		posted = entry
+		Resp.Balance = user.Balance
+		return saveResponse(ctx, tx, Req.IdempotencyKey, hash, Resp)
+	})
 	if err != nil{
 		return
 	}
-	log.Trace("changed balance, result: " + fmt.Sprintf("%#v", Resp))
+	countPosted(posted)
+	logging.FromContext(ctx).WithField("result", Resp).Trace("changed balance")
 	return
 }
 
-func (d *dbClient) UpdateBalances(Req *m.TransferReq) (Resp *m.TransferResp, err error){
-	var exists bool
-	Resp = &m.TransferResp{
-		Source: m.ChangeBalanceResp{UserId: Req.UserId},
-		Target: m.ChangeBalanceResp{UserId: Req.TargetId, Balance: Req.Change},
-	}
-	sourceTrans := &m.Transaction{
-		Change: -Req.Change,
-		UserId: Req.UserId,
-		Comment: Req.Comment,
-		Source: strconv.Itoa(Req.UserId),
-	}
-	targetTrans := &m.Transaction{
-		Change: Req.Change,
-		UserId: Req.TargetId,
-		Comment: Req.Comment,
-		Source: strconv.Itoa(Req.UserId),
-	}
-	tx, err := d.db.Beginx()
-	if err != nil{
+// ReplayTransfer returns the response stored for the idempotency key of the transfer, nil if the key
+// isn't used yet. A retried transfer is answered without pricing it again.
+func (d *dbClient) ReplayTransfer(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error){
+	if Req.IdempotencyKey == ""{
 		return
 	}
-	_, err = tx.Exec(SetIsolationSerializable)
+	hash, err := requestHash(Req)
 	if err != nil{
 		return
 	}
-	log.Trace("start transaction with data: " + fmt.Sprintf("%#v", Req))
-	err = tx.QueryRow(CheckExistence, sourceTrans.UserId).Scan(&exists)
+	err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+		Resp = &m.TransferResp{}
+		replayed, err := replayResponse(ctx, tx, Req.IdempotencyKey, hash, Resp)
+		if !replayed{
+			Resp = nil
+		}
+		return
+	})
+	return
+}
+
+func (d *dbClient) UpdateBalances(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error){
+	hash, err := requestHash(Req)
 	if err != nil{
-		err = rollAndErr(tx, err)
 		return
 	}
-	if !exists{
-		_ = rollAndErr(tx, err)
-		err = errors.New("user don't have balance")
+	var posted *ledgerEntry
+	err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+		posted = nil
+		Resp = &m.TransferResp{
+			Rate: Req.Rate,
+			Source: m.ChangeBalanceResp{UserId: Req.UserId, Currency: Req.Currency},
+			Target: m.ChangeBalanceResp{UserId: Req.TargetId, Currency: Req.TargetCurrency},
+		}
+		sourceChange, targetChange := -Req.Change, Req.TargetChange
+		sourceTrans := &m.Transaction{
+			Change: sourceChange,
+			Currency: Req.Currency,
+			UserId: Req.UserId,
+			Comment: Req.Comment,
+			Source: strconv.Itoa(Req.UserId),
+			Rate: &Req.Rate,
+			CounterChange: &targetChange,
+			CounterCurrency: &Req.TargetCurrency,
+		}
+		targetTrans := &m.Transaction{
+			Change: targetChange,
+			Currency: Req.TargetCurrency,
+			UserId: Req.TargetId,
+			Comment: Req.Comment,
+			Source: strconv.Itoa(Req.UserId),
+			Rate: &Req.Rate,
+			CounterChange: &sourceChange,
+			CounterCurrency: &Req.Currency,
+		}
+		logging.FromContext(ctx).WithFields(Req.LogFields()).Trace("start transaction")
+		replayed, err := replayResponse(ctx, tx, Req.IdempotencyKey, hash, Resp)
+		if err != nil || replayed{
+			return
+		}
+		err = tx.QueryRowContext(ctx, InsertTransfer, Req.UserId, Req.TargetId, Req.Change, Req.Currency,
+			Req.TargetChange, Req.TargetCurrency, Req.Rate, Req.Comment, time.Now()).Scan(&Resp.TransferId)
+		if err != nil{
+			return
+		}
+		sourceTrans.TransferId = &Resp.TransferId
+		targetTrans.TransferId = &Resp.TransferId
+		source, target := userLeg(sourceTrans), userLeg(targetTrans)
+		target.OpenWallet = true
+		entry := &ledgerEntry{Kind: entryTransfer, Legs: []*ledgerLeg{source, target}}
+		// money changes the currency on the exchange account
+		err = entry.balance(map[string]string{Req.Currency: m.AccountFX, Req.TargetCurrency: m.AccountFX})
+		if err != nil{
+			return
+		}
+		err = post(ctx, tx, entry)
+		if err != nil{
+			return
+		}
+		posted = entry
+		Resp.Source.Balance, Resp.Target.Balance = source.Balance, target.Balance
+		return saveResponse(ctx, tx, Req.IdempotencyKey, hash, Resp)
+	})
+	if err != nil{
 		return
 	}
-	err = tx.QueryRow(SelectUserBalance, Req.UserId).Scan(&sourceTrans.InitialBalance)
-	if err != nil{
-			err = rollAndErr(tx, err)
+	countPosted(posted)
+	logging.FromContext(ctx).WithField("result", Resp).Trace("changed balances")
+	return
+}
+
+func (d *dbClient) ReserveBalance(ctx context.Context, Req *m.ReserveReq) (Resp *m.HoldResp, err error){
+	err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+		var exists bool
+		var balance, held m.Money
+		Resp = &m.HoldResp{
+			UserId: Req.UserId,
+			Amount: Req.Amount,
+			Currency: Req.Currency,
+			Status: m.HoldHeld,
+		}
+		logging.FromContext(ctx).WithFields(Req.LogFields()).Trace("start transaction")
+		err = tx.QueryRowContext(ctx, CheckExistence, Req.UserId, Req.Currency).Scan(&exists)
+		if err != nil{
 			return
 		}
-	Resp.Source.Balance, err = changeBalance(tx, sourceTrans)
-	if err != nil{
-			err = rollAndErr(tx, err)
+		if !exists{
+			return m.ErrWalletNotFound
+		}
+		err = tx.QueryRowContext(ctx, SelectUserBalance, Req.UserId, Req.Currency).Scan(&balance, &held)
+		if err != nil{
 			return
 		}
-
-	existed, err := createNewUser(tx, targetTrans)
+		if balance - held - Req.Amount < 0{
+			return m.ErrInsufficientFunds
+		}
+		err = tx.QueryRowContext(ctx, InsertHold, Req.UserId, Req.Amount, Req.Currency, m.HoldHeld, Req.Comment, time.Now()).Scan(&Resp.HoldId)
+		if err != nil{
+			return
+		}
+		return tx.QueryRowContext(ctx, UpdateUserHeld, Req.Amount, Req.UserId, Req.Currency).Scan(&Resp.Balance, &Resp.Held)
+	})
 	if err != nil{
-		err = rollAndErr(tx, err)
 		return
 	}
-	if existed{
-		err = tx.QueryRow(SelectUserBalance, Req.TargetId).Scan(&targetTrans.InitialBalance)
+	logging.FromContext(ctx).WithField("result", Resp).Trace("reserved balance")
+	return
+}
+
+// settleHold captures or releases a hold. A captured hold is debited from the balance
+// and written to Transactions, a released one just returns the money to the available balance.
+func (d *dbClient) settleHold(ctx context.Context, Req *m.HoldReq, capture bool) (Resp *m.HoldResp, err error){
+	var posted *ledgerEntry
+	err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+		posted = nil
+		hold := &m.Hold{}
+		logging.FromContext(ctx).WithFields(Req.LogFields()).Trace("start transaction")
+		err = tx.QueryRowxContext(ctx, SelectHold, Req.HoldId, Req.UserId).StructScan(hold)
+		if err == sql.ErrNoRows{
+			return m.ErrHoldNotFound
+		}
 		if err != nil{
-			err = rollAndErr(tx, err)
 			return
 		}
-		Resp.Target.Balance, err = changeBalance(tx, targetTrans)
+		if hold.Status != m.HoldHeld{
+			return m.NewConflictError(m.CodeHoldSettled, "hold is already " + hold.Status)
+		}
+		Resp = &m.HoldResp{
+			HoldId: hold.HoldId,
+			UserId: hold.UserId,
+			Amount: hold.Amount,
+			Currency: hold.Currency,
+			Status: m.HoldReleased,
+		}
+		if capture{
+			Resp.Status = m.HoldCaptured
+			trans := &m.Transaction{
+				UserId: hold.UserId,
+				Change: -hold.Amount,
+				Currency: hold.Currency,
+				Comment: hold.Comment,
+				Source: "hold:" + strconv.Itoa(hold.HoldId),
+			}
+			user := userLeg(trans)
+			// the captured amount is still counted in held, so it is returned before the debit check
+			user.Released = hold.Amount
+			entry := &ledgerEntry{
+				Kind: entryCapture,
+				Legs: []*ledgerLeg{user, systemLeg(m.AccountPayments, hold.Currency, hold.Amount)},
+			}
+			err = post(ctx, tx, entry)
+			if err != nil{
+				return
+			}
+			posted = entry
+		}
+		_, err = tx.ExecContext(ctx, UpdateHoldStatus, Resp.Status, hold.HoldId)
 		if err != nil{
-			err = rollAndErr(tx, err)
 			return
 		}
+		return tx.QueryRowContext(ctx, UpdateUserHeld, -hold.Amount, hold.UserId, hold.Currency).Scan(&Resp.Balance, &Resp.Held)
+	})
+	if err != nil{
+		return
+	}
+	countPosted(posted)
+	logging.FromContext(ctx).WithField("result", Resp).Trace("settled hold")
+	return
+}
+
+func (d *dbClient) CaptureHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error){
+	return d.settleHold(ctx, Req, true)
+}
+
+func (d *dbClient) ReleaseHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error){
+	return d.settleHold(ctx, Req, false)
+}
+
+// counterRefund returns the refund amount for the other leg of a transfer in its currency.
+// The last refund takes all that remains, so rounding never leaves a kopeck on either leg.
+func counterRefund(ctx context.Context, tx *sqlx.Tx, leg *m.Transaction, orig *m.Transaction, amount m.Money, remaining m.Money) (counter m.Money, err error){
+	total, origTotal := leg.Change, orig.Change
+	if total < 0{
+		total = -total
+	}
+	if origTotal < 0{
+		origTotal = -origTotal
 	}
-	err = tx.Commit()
+	if remaining > 0{
+		counter = amount.MulDiv(total, origTotal)
+		return
+	}
+	err = tx.QueryRowContext(ctx, SelectRefunded, leg.TransId).Scan(&counter)
 	if err != nil{
 		return
 	}
-	log.Trace("changed balances, result: " + fmt.Sprintf("%#v", Resp))
+	if counter < 0{
+		counter = -counter
+	}
+	counter = total - counter
 	return
 }
-func (d *dbClient) SelectBalance(Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error){
-	var exists bool
-	Resp = &m.GetBalanceResp{UserId: Req.UserId}
-	tx, err := d.db.Beginx()
-	log.Trace("start transaction with data: " + fmt.Sprintf("%#v", Req))
+
+// RefundTransaction writes compensating entries for a transaction. A transfer is refunded on both legs,
+// so the money returns from the target to the source. Partial refunds are summed up and can't exceed
+// the original amount.
+func (d *dbClient) RefundTransaction(ctx context.Context, Req *m.RefundReq) (Resp *m.RefundResp, err error){
+	hash, err := requestHash(Req)
 	if err != nil{
 		return
 	}
-	err = tx.QueryRow(CheckExistence, Req.UserId).Scan(&exists)
-	if !exists{
-		_ = rollAndErr(tx, err)
-		err = errors.New("user don't have balance")
+	var posted *ledgerEntry
+	err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+		posted = nil
+		var refunded m.Money
+		orig := &m.Transaction{}
+		legs := []m.Transaction{}
+		Resp = &m.RefundResp{
+			TransId: Req.TransId,
+			Balances: []m.ChangeBalanceResp{},
+		}
+		logging.FromContext(ctx).WithFields(Req.LogFields()).Trace("start transaction")
+		replayed, err := replayResponse(ctx, tx, Req.IdempotencyKey, hash, Resp)
+		if err != nil || replayed{
+			return
+		}
+		err = tx.QueryRowxContext(ctx, SelectTransaction, Req.TransId, Req.UserId).StructScan(orig)
+		if err == sql.ErrNoRows{
+			return m.ErrTransactionNotFound
+		}
+		if err != nil{
+			return
+		}
+		if Req.Authorize != nil{
+			err = Req.Authorize(orig)
+			if err != nil{
+				return
+			}
+		}
+		if orig.RefundOf != nil{
+			return m.NewConflictError(m.CodeRefundNotAllowed, "refund can't be refunded")
+		}
+		if orig.Source == m.SourceReconciliation{
+			return m.NewConflictError(m.CodeRefundNotAllowed, "correction can't be refunded")
+		}
+		err = tx.QueryRowContext(ctx, SelectRefunded, orig.TransId).Scan(&refunded)
+		if err != nil{
+			return
+		}
+		total := orig.Change
+		if total < 0{
+			total = -total
+		}
+		if refunded < 0{
+			refunded = -refunded
+		}
+		if refunded >= total{
+			return m.NewConflictError(m.CodeRefundExceeded, "transaction is already refunded")
+		}
+		Resp.Amount = Req.Amount
+		if Resp.Amount == 0{
+			Resp.Amount = total - refunded
+		}
+		if Resp.Amount > total - refunded{
+			return m.NewConflictError(m.CodeRefundExceeded, "refund exceeds remaining amount")
+		}
+		Resp.Remaining = total - refunded - Resp.Amount
+		if orig.TransferId != nil{
+			err = tx.SelectContext(ctx, &legs, SelectTransferLegs, *orig.TransferId)
+			if err != nil{
+				return
+			}
+		} else{
+			legs = append(legs, *orig)
+		}
+		entry := &ledgerEntry{Kind: entryRefund}
+		currencies := []string{}
+		for i := range legs{
+			leg := &legs[i]
+			amount := Resp.Amount
+			if leg.TransId != orig.TransId{
+				amount, err = counterRefund(ctx, tx, leg, orig, Resp.Amount, Resp.Remaining)
+				if err != nil{
+					return
+				}
+			}
+			trans := &m.Transaction{
+				UserId: leg.UserId,
+				Change: amount,
+				Currency: leg.Currency,
+				Comment: Req.Comment,
+				Source: "refund",
+				RefundOf: &leg.TransId,
+			}
+			if leg.Change > 0{
+				trans.Change = -amount
+			}
+			entry.Legs = append(entry.Legs, userLeg(trans))
+			currencies = append(currencies, leg.Currency)
+		}
+		// the refund goes back to the system accounts of the original entry
+		accounts, err := entryAccounts(ctx, tx, orig.TransId, currencies)
+		if err != nil{
+			return
+		}
+		err = entry.balance(accounts)
+		if err != nil{
+			return
+		}
+		err = post(ctx, tx, entry)
+		if err != nil{
+			return
+		}
+		posted = entry
+		for _, leg := range entry.Legs{
+			if leg.Trans != nil{
+				Resp.Balances = append(Resp.Balances, m.ChangeBalanceResp{
+					UserId: leg.Trans.UserId,
+					Balance: leg.Balance,
+					Currency: leg.Currency,
+				})
+			}
+		}
+		return saveResponse(ctx, tx, Req.IdempotencyKey, hash, Resp)
+	})
+	if err != nil{
 		return
 	}
-	err = tx.QueryRow(SelectUserBalance, Req.UserId).Scan(&Resp.Balance)
+	countPosted(posted)
+	logging.FromContext(ctx).WithField("result", Resp).Trace("refunded transaction")
+	return
+}
+
+// balancesAsOf reads the currency balances of the users at the instant asOf: init_balance + change
+// of the last transaction before it. Corrections of reconciliation are left out, they fill gaps in the past.
+// Users without the wallet, or whose wallet has no transaction before asOf, are skipped.
+func balancesAsOf(ctx context.Context, tx *sqlx.Tx, userIds []int, currency string, asOf time.Time) (balances []m.GetBalanceResp, err error){
+	rows := []struct{
+		UserId int `db:"user_id"`
+		Balance m.Money `db:"balance"`
+	}{}
+	query, args, err := sqlx.In(SelectBalancesAsOf, asOf, userIds, currency)
 	if err != nil{
-		err = rollAndErr(tx, err)
 		return
 	}
-	err = tx.Commit()
+	err = tx.SelectContext(ctx, &rows, tx.Rebind(query), args...)
 	if err != nil{
 		return
 	}
+	balances = make([]m.GetBalanceResp, 0, len(rows))
+	for _, row := range rows{
+		at := asOf
+		balances = append(balances, m.GetBalanceResp{
+			UserId: row.UserId,
+			Balance: row.Balance,
+			Available: row.Balance,
+			Currency: currency,
+			AsOf: &at,
+		})
+	}
 	return
 }
-func (d *dbClient) SelectTransactions(Req *m.GetTransactionsReq) (Resp *m.Transactions, err error){
+
+func (d *dbClient) SelectBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error){
+	err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+		var exists bool
+		Resp = &m.GetBalanceResp{UserId: Req.UserId, Currency: Req.Wallet}
+		logging.FromContext(ctx).WithFields(Req.LogFields()).Trace("start transaction")
+		if !Req.AsOf.IsZero(){
+			balances, err := balancesAsOf(ctx, tx, []int{Req.UserId}, Req.Wallet, Req.AsOf)
+			if err != nil{
+				return err
+			}
+			if len(balances) == 0{
+				return m.ErrWalletNotFound
+			}
+			Resp = &balances[0]
+			return nil
+		}
+		err = tx.QueryRowContext(ctx, CheckExistence, Req.UserId, Req.Wallet).Scan(&exists)
+		if err != nil{
+			return
+		}
+		if !exists{
+			return m.ErrWalletNotFound
+		}
+		err = tx.QueryRowContext(ctx, SelectUserBalance, Req.UserId, Req.Wallet).Scan(&Resp.Balance, &Resp.Held)
+		if err != nil{
+			return
+		}
+		Resp.Available = Resp.Balance - Resp.Held
+		return
+	})
+	return
+}
+
+func (d *dbClient) SelectBalances(ctx context.Context, Req *m.GetBalancesReq) (Resp *m.GetBalancesResp, err error){
+	err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+		Resp = &m.GetBalancesResp{AsOf: Req.AsOf, Currency: Req.Wallet, Missing: []int{}}
+		logging.FromContext(ctx).WithFields(Req.LogFields()).Trace("start transaction")
+		Resp.Balances, err = balancesAsOf(ctx, tx, Req.UserIds, Req.Wallet, Req.AsOf)
+		if err != nil{
+			return
+		}
+		found := map[int]bool{}
+		for _, b := range Resp.Balances{
+			found[b.UserId] = true
+		}
+		for _, id := range Req.UserIds{
+			if !found[id]{
+				found[id] = true
+				Resp.Missing = append(Resp.Missing, id)
+			}
+		}
+		return
+	})
+	return
+}
+// selectTransactionsQuery builds a keyset query for one page of transactions. One row more than
+// the page size is requested to find out whether there is a next page. The deprecated page number
+// without a cursor skips rows by OFFSET, which is bounded by m.MaxPageOffset.
+func selectTransactionsQuery(Req *m.GetTransactionsReq) (query string, args []interface{}){
+	offset := 0
+	query = SelectTransactions
+	args = []interface{}{Req.UserId}
+	if !Req.From.IsZero(){
+		query += fmt.Sprintf(TimeFrom, len(args) + 1)
+		args = append(args, Req.From)
+	}
+	if !Req.To.IsZero(){
+		query += fmt.Sprintf(TimeTo, len(args) + 1)
+		args = append(args, Req.To)
+	}
+	if Req.After != nil{
+		switch{
+		case Req.ChangeSort:
+			query += fmt.Sprintf(AfterChange, len(args) + 1, len(args) + 2)
+			args = append(args, Req.After.Change, Req.After.TransId)
+		case Req.TimeSort:
+			query += fmt.Sprintf(AfterTime, len(args) + 1, len(args) + 2)
+			args = append(args, Req.After.Time, Req.After.TransId)
+		default:
+			query += fmt.Sprintf(AfterTransId, len(args) + 1)
+			args = append(args, Req.After.TransId)
+		}
+	} else if Req.Page > 1{
+		offset = (Req.Page - 1) * Req.TransactionsOnPage
+	}
+	switch{
+	case Req.ChangeSort:
+		query += OrderByChange
+	case Req.TimeSort:
+		query += OrderByTime
+	default:
+		query += OrderByTransId
+	}
+	query += fmt.Sprintf(LimitOffset, len(args) + 1, len(args) + 2)
+	args = append(args, Req.TransactionsOnPage + 1, offset)
+	return
+}
+
+func (d *dbClient) SelectTransactions(ctx context.Context, Req *m.GetTransactionsReq) (Resp *m.Transactions, err error){
 	Resp = &m.Transactions{
 		Transactions: []m.Transaction{},
-		ChangeSort:   Req.ChangeSort,
-		TimeSort:     Req.TimeSort,
 	}
-    err = d.db.Select(&Resp.Transactions, SelectTransactions, Req.UserId)
+	query, args := selectTransactionsQuery(Req)
+    err = d.db.SelectContext(ctx, &Resp.Transactions, query, args...)
     if err != nil{
+    	err = dbError(err)
     	return
 	}
-	log.Trace(Resp)
+	if len(Resp.Transactions) > Req.TransactionsOnPage{
+		Resp.Transactions = Resp.Transactions[:Req.TransactionsOnPage]
+		Resp.HasMore = true
+	}
+	logging.FromContext(ctx).WithField("count", len(Resp.Transactions)).Trace("selected transactions")
 	return
 }
 
+func (d *dbClient) SelectTransfer(ctx context.Context, Req *m.GetTransferReq) (Resp *m.Transfer, err error){
+	Resp = &m.Transfer{
+		Legs: []m.Transaction{},
+		Refunds: []m.Transaction{},
+	}
+	err = d.db.QueryRowxContext(ctx, SelectTransfer, Req.TransferId).StructScan(Resp)
+	if err == sql.ErrNoRows{
+		err = m.ErrTransferNotFound
+		return
+	}
+	if err != nil{
+		err = dbError(err)
+		return
+	}
+	err = d.db.SelectContext(ctx, &Resp.Legs, SelectTransferTransactions, Req.TransferId)
+	if err != nil{
+		err = dbError(err)
+		return
+	}
+	err = d.db.SelectContext(ctx, &Resp.Refunds, SelectTransferRefunds, Req.TransferId)
+	if err != nil{
+		err = dbError(err)
+		return
+	}
+	logging.FromContext(ctx).WithField("result", Resp).Trace("selected transfer")
+	return
+}
+
+// dbError marks connection failures as unavailability of the database.
+func dbError(err error) error{
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr){
+		return m.NewUnavailableError(m.CodeDatabaseUnavailable, "database is unavailable", err)
+	}
+	return err
+}
+
+func (d *dbClient) Ping(ctx context.Context) error{
+	return d.db.PingContext(ctx)
+}
+
 func (d *dbClient) Shutdown() error{
 	return d.db.Close()
 }
 
-func NewDbClient() DbClient{
-	db, err := sqlx.Connect("pgx", os.Getenv("DATABASE_URL"))
+func NewDbClient(cfg config.Postgres) DbClient{
+	db, err := connect(cfg.URL)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//db.SetMaxIdleConns(n int)
-	//db.SetMaxOpenConns(n int)
-	return &dbClient{db: db}
-}
\ No newline at end of file
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime.Std())
+	return &dbClient{
+		db: db,
+		maxRetries: cfg.MaxRetries,
+		pool: collectors.NewDBStatsCollector(db.DB, "avitojob"),
+	}
+}
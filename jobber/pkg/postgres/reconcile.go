@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+const(
+	SelectWallets = `SELECT user_id, currency FROM Wallets ORDER BY user_id, currency;`
+	SelectWalletBalance = `SELECT balance FROM Wallets WHERE user_id=$1 AND currency=$2 FOR UPDATE;`
+	SelectWalletTransactions = `SELECT * FROM Transactions WHERE user_id=$1 AND currency=$2 ORDER BY trans_id;`
+	SelectAccountSum = `SELECT COALESCE(SUM(p.amount), 0)::bigint FROM Postings p JOIN Accounts a ON a.account_id = p.account_id
+                     WHERE a.name=$1 AND a.currency=$2;`
+)
+
+type wallet struct{
+	UserId int `db:"user_id"`
+	Currency string `db:"currency"`
+}
+
+// checkHistory walks the history of a wallet in trans_id order. Every transaction has to start at the
+// balance the previous one left, and the last one has to leave the balance of the wallet. Corrections
+// recorded by earlier runs fill the gaps before the transactions they correct.
+func checkHistory(w wallet, balance m.Money, trs []m.Transaction) (found []m.Discrepancy){
+	var sum, running m.Money
+	gaps := map[int]m.Money{}
+	for _, tr := range trs{
+		sum += tr.Change
+		if tr.Corrects != nil{
+			gaps[*tr.Corrects] += tr.Change
+		}
+	}
+	for _, tr := range trs{
+		if tr.Corrects != nil{
+			continue
+		}
+		running += gaps[tr.TransId]
+		if tr.InitialBalance != running{
+			transId := tr.TransId
+			found = append(found, m.Discrepancy{
+				UserId: w.UserId,
+				Currency: w.Currency,
+				Kind: m.DiscrepancyChain,
+				TransId: &transId,
+				Expected: running,
+				Actual: tr.InitialBalance,
+			})
+			running = tr.InitialBalance
+		}
+		running += tr.Change
+	}
+	if running != balance{
+		found = append(found, m.Discrepancy{
+			UserId: w.UserId,
+			Currency: w.Currency,
+			Kind: m.DiscrepancyChain,
+			Expected: running,
+			Actual: balance,
+		})
+	}
+	if sum != balance{
+		found = append(found, m.Discrepancy{
+			UserId: w.UserId,
+			Currency: w.Currency,
+			Kind: m.DiscrepancyBalance,
+			Expected: sum,
+			Actual: balance,
+		})
+	}
+	return
+}
+
+// reconcileWallet checks one wallet. A broken chain means a change missing from the history, in repair
+// mode it is recorded as a corrective transaction booked against AccountAdjustments. The balance itself
+// is never changed: it is what the user has seen.
+func reconcileWallet(ctx context.Context, tx *sqlx.Tx, w wallet, repair bool) (found []m.Discrepancy, err error){
+	var balance, ledger, missing m.Money
+	trs := []m.Transaction{}
+	err = tx.QueryRowContext(ctx, SelectWalletBalance, w.UserId, w.Currency).Scan(&balance)
+	if err != nil{
+		return
+	}
+	err = tx.SelectContext(ctx, &trs, SelectWalletTransactions, w.UserId, w.Currency)
+	if err != nil{
+		return
+	}
+	err = tx.QueryRowContext(ctx, SelectAccountSum, m.UserAccount(w.UserId), w.Currency).Scan(&ledger)
+	if err != nil{
+		return
+	}
+	found = checkHistory(w, balance, trs)
+	for i := range found{
+		d := &found[i]
+		if d.Kind != m.DiscrepancyChain{
+			continue
+		}
+		missing += d.Actual - d.Expected
+		if !repair{
+			continue
+		}
+		correction := userLeg(&m.Transaction{
+			UserId: w.UserId,
+			InitialBalance: d.Expected,
+			Change: d.Actual - d.Expected,
+			Currency: w.Currency,
+			Source: m.SourceReconciliation,
+			Comment: "reconciliation correction",
+			Corrects: d.TransId,
+		})
+		correction.Recorded = true
+		err = post(ctx, tx, &ledgerEntry{
+			Kind: entryCorrection,
+			Legs: []*ledgerLeg{correction, systemLeg(m.AccountAdjustments, w.Currency, -correction.Amount)},
+		})
+		if err != nil{
+			return
+		}
+		d.Repaired = true
+		d.Correction = &correction.Trans.TransId
+	}
+	if repair{
+		for i := range found{
+			if found[i].Kind == m.DiscrepancyBalance{
+				found[i].Repaired = true
+			}
+		}
+	}
+	// corrections move the user account as well, so only the rest of the difference is a ledger error
+	if ledger + missing != balance{
+		found = append(found, m.Discrepancy{
+			UserId: w.UserId,
+			Currency: w.Currency,
+			Kind: m.DiscrepancyLedger,
+			Expected: balance - missing,
+			Actual: ledger,
+		})
+	}
+	return
+}
+
+// Reconcile checks every wallet against its transactions and ledger account, one wallet per transaction.
+// In repair mode the missing changes are recorded as corrective transactions.
+func (d *dbClient) Reconcile(ctx context.Context, repair bool) (Resp *m.ReconciliationReport, err error){
+	wallets := []wallet{}
+	report := &m.ReconciliationReport{
+		Started: time.Now(),
+		Repair: repair,
+		Discrepancies: []m.Discrepancy{},
+	}
+	err = d.db.SelectContext(ctx, &wallets, SelectWallets)
+	if err != nil{
+		return nil, dbError(err)
+	}
+	for _, w := range wallets{
+		var found []m.Discrepancy
+		err = d.inTx(ctx, func(tx *sqlx.Tx) (err error){
+			found, err = reconcileWallet(ctx, tx, w, repair)
+			return
+		})
+		if err != nil{
+			return nil, fmt.Errorf("reconcile wallet %s of user %d: %w", w.Currency, w.UserId, err)
+		}
+		report.Discrepancies = append(report.Discrepancies, found...)
+	}
+	report.Wallets = len(wallets)
+	report.Finished = time.Now()
+	log.Trace("reconciled " + fmt.Sprint(report.Wallets) + " wallets, found " + fmt.Sprint(len(report.Discrepancies)) + " discrepancies")
+	return report, nil
+}
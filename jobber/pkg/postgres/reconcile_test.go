@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"testing"
+
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+)
+
+func intPtr(i int) *int{
+	return &i
+}
+
+func TestCheckHistory(t *testing.T){
+	w := wallet{UserId: 1, Currency: "RUB"}
+	cases := []struct{
+		Balance m.Money
+		Trs     []m.Transaction
+		Found   []m.Discrepancy
+	}{
+		{
+			Balance: 15000,
+			Trs: []m.Transaction{
+				{TransId: 1, InitialBalance: 0, Change: 10000},
+				{TransId: 2, InitialBalance: 10000, Change: 5000},
+			},
+		},
+		{
+			// the balance was raised in place between the transactions
+			Balance: 17000,
+			Trs: []m.Transaction{
+				{TransId: 1, InitialBalance: 0, Change: 10000},
+				{TransId: 2, InitialBalance: 12000, Change: 5000},
+			},
+			Found: []m.Discrepancy{
+				{Kind: m.DiscrepancyChain, TransId: intPtr(2), Expected: 10000, Actual: 12000},
+				{Kind: m.DiscrepancyBalance, Expected: 15000, Actual: 17000},
+			},
+		},
+		{
+			// and after the last one
+			Balance: 14000,
+			Trs: []m.Transaction{
+				{TransId: 1, InitialBalance: 0, Change: 10000},
+				{TransId: 2, InitialBalance: 10000, Change: 5000},
+			},
+			Found: []m.Discrepancy{
+				{Kind: m.DiscrepancyChain, Expected: 15000, Actual: 14000},
+				{Kind: m.DiscrepancyBalance, Expected: 15000, Actual: 14000},
+			},
+		},
+		{
+			// both gaps are filled by corrections of an earlier run
+			Balance: 16000,
+			Trs: []m.Transaction{
+				{TransId: 1, InitialBalance: 0, Change: 10000},
+				{TransId: 2, InitialBalance: 12000, Change: 5000},
+				{TransId: 3, InitialBalance: 10000, Change: 2000, Source: m.SourceReconciliation, Corrects: intPtr(2)},
+				{TransId: 4, InitialBalance: 17000, Change: -1000, Source: m.SourceReconciliation},
+				{TransId: 5, InitialBalance: 16000, Change: 500},
+				{TransId: 6, InitialBalance: 16500, Change: -500},
+			},
+		},
+	}
+	for num, c := range cases{
+		found := checkHistory(w, c.Balance, c.Trs)
+		if len(found) != len(c.Found){
+			t.Errorf("[%d] unexpected discrepancies: %+v, expected: %+v", num, found, c.Found)
+			continue
+		}
+		for i, d := range found{
+			exp := c.Found[i]
+			if d.Kind != exp.Kind || d.Expected != exp.Expected || d.Actual != exp.Actual ||
+				(d.TransId == nil) != (exp.TransId == nil) || (d.TransId != nil && *d.TransId != *exp.TransId){
+				t.Errorf("[%d] unexpected discrepancy: %+v, expected: %+v", num, d, exp)
+			}
+			if d.UserId != w.UserId || d.Currency != w.Currency{
+				t.Errorf("[%d] unexpected wallet of discrepancy: %+v", num, d)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"github.com/jmoiron/sqlx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+)
+
+// statementNames names the spans of statements by their constants.
+var statementNames = map[string]string{
+	CheckExistence: "CheckExistence",
+	SelectUserBalance: "SelectUserBalance",
+	InsertUser: "InsertUser",
+	InsertWallet: "InsertWallet",
+	UpdateUserBalance: "UpdateUserBalance",
+	UpdateUserHeld: "UpdateUserHeld",
+	InsertHold: "InsertHold",
+	SelectHold: "SelectHold",
+	UpdateHoldStatus: "UpdateHoldStatus",
+	SetIsolationSerializable: "SetIsolationSerializable",
+	InsertTransfer: "InsertTransfer",
+	SelectTransfer: "SelectTransfer",
+	SelectTransferTransactions: "SelectTransferTransactions",
+	SelectTransferRefunds: "SelectTransferRefunds",
+	SelectTransaction: "SelectTransaction",
+	SelectTransferLegs: "SelectTransferLegs",
+	SelectRefunded: "SelectRefunded",
+	SelectIdempotencyKey: "SelectIdempotencyKey",
+	InsertIdempotencyKey: "InsertIdempotencyKey",
+	InsertAccount: "InsertAccount",
+	SelectAccount: "SelectAccount",
+	InsertEntry: "InsertEntry",
+	InsertPosting: "InsertPosting",
+	SelectEntrySystemAccounts: "SelectEntrySystemAccounts",
+	SelectUnbalancedCurrencies: "SelectUnbalancedCurrencies",
+	SelectUnbalancedEntries: "SelectUnbalancedEntries",
+	SelectWallets: "SelectWallets",
+	SelectWalletBalance: "SelectWalletBalance",
+	SelectWalletTransactions: "SelectWalletTransactions",
+	SelectAccountSum: "SelectAccountSum",
+	CreateMigrationsTable: "CreateMigrationsTable",
+	LockMigrations: "LockMigrations",
+	SelectMigrations: "SelectMigrations",
+	InsertMigration: "InsertMigration",
+	DeleteMigration: "DeleteMigration",
+}
+
+// statementPrefixes name the statements built from a constant and filters.
+var statementPrefixes = map[string]string{
+	SelectTransactions: "SelectTransactions",
+}
+
+func init(){
+	// named statements are sent with the placeholders bound by sqlx
+	insertTrans, _, err := sqlx.BindNamed(sqlx.DOLLAR, InsertTrans, &m.Transaction{})
+	if err != nil{
+		panic(err)
+	}
+	statementNames[insertTrans] = "InsertTrans"
+	// statements expanded by sqlx.In are known by their text up to the first bindvar
+	statementPrefixes[SelectBalancesAsOf[:strings.Index(SelectBalancesAsOf, "?")]] = "SelectBalancesAsOf"
+}
+
+type spanNames struct{}
+
+// Format names the span of a statement after its constant. Other statements, like migration
+// scripts, are named by their first keyword.
+func (spanNames) Format(ctx context.Context, method otelsql.Method, query string) string{
+	if name, ok := statementNames[query]; ok{
+		return "postgres " + name
+	}
+	for prefix, name := range statementPrefixes{
+		if strings.HasPrefix(query, prefix){
+			return "postgres " + name
+		}
+	}
+	if fields := strings.Fields(query); len(fields) > 0{
+		return "postgres " + strings.ToUpper(fields[0])
+	}
+	return string(method)
+}
+
+// connect opens the database with a span for every statement.
+func connect(url string) (db *sqlx.DB, err error){
+	sqlDB, err := otelsql.Open("pgx", url,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanNameFormatter(spanNames{}))
+	if err != nil{
+		return
+	}
+	db = sqlx.NewDb(sqlDB, "pgx")
+	err = db.Ping()
+	if err != nil{
+		db.Close()
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+)
+
+func TestSpanNames(t *testing.T){
+	insertTrans, _, err := sqlx.BindNamed(sqlx.DOLLAR, InsertTrans, &m.Transaction{})
+	if err != nil{
+		t.Fatal(err)
+	}
+	balancesAsOf, _, err := sqlx.In(SelectBalancesAsOf, time.Now(), []int{1, 2, 3}, "RUB")
+	if err != nil{
+		t.Fatal(err)
+	}
+	cases := []struct{
+		Query string
+		Name  string
+	}{
+		{Query: CheckExistence, Name: "postgres CheckExistence"},
+		{Query: UpdateUserBalance, Name: "postgres UpdateUserBalance"},
+		{Query: insertTrans, Name: "postgres InsertTrans"},
+		{Query: SelectTransactions + fmt.Sprintf(TimeFrom, 2) + OrderByTime, Name: "postgres SelectTransactions"},
+		{Query: sqlx.Rebind(sqlx.DOLLAR, balancesAsOf), Name: "postgres SelectBalancesAsOf"},
+		{Query: "\nALTER TABLE Transactions ADD COLUMN corrects integer;", Name: "postgres ALTER"},
+		{Query: "", Name: "sql.conn.begin_tx"},
+	}
+	for num, c := range cases{
+		name := spanNames{}.Format(context.Background(), "sql.conn.begin_tx", c.Query)
+		if name != c.Name{
+			t.Errorf("[%d] unexpected span name: %s, expected: %s", num, name, c.Name)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
+	"github.com/fedorkolmykow/avitojob/pkg/metrics"
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+	"github.com/fedorkolmykow/avitojob/pkg/tracing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const(
+	retryBaseDelay = 10 * time.Millisecond
+	retryMaxDelay = time.Second
+)
+
+// SQLSTATE codes of errors after which the whole transaction can be run again.
+const(
+	serializationFailure = "40001"
+	deadlockDetected = "40P01"
+)
+
+// attemptAttribute numbers the runs of a transaction from 0.
+const attemptAttribute = attribute.Key("db.transaction.attempt")
+
+// RetryStats counts reruns of transactions since the start of the client.
+type RetryStats struct{
+	// Retries is the number of reruns after serialization failures and deadlocks.
+	Retries uint64
+	// Exhausted is the number of transactions that still failed after the last rerun.
+	Exhausted uint64
+}
+
+// retryable reports whether err is a serialization failure or a deadlock.
+func retryable(err error) bool{
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr){
+		return false
+	}
+	state := pgErr.SQLState()
+	return state == serializationFailure || state == deadlockDetected
+}
+
+// backoff returns a random delay up to retryBaseDelay doubled attempt times.
+func backoff(attempt int) time.Duration{
+	limit := retryBaseDelay << uint(attempt)
+	if limit <= 0 || limit > retryMaxDelay{
+		limit = retryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(limit))) + 1
+}
+
+// inTx runs fn in a serializable transaction, which is rolled back when fn fails and committed otherwise.
+// Serialization failures and deadlocks rerun fn from the start after a jittered backoff, at most
+// d.maxRetries times and while ctx isn't done, so fn must build its results from scratch on every run.
+func (d *dbClient) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) (err error){
+	for attempt := 0; ; attempt++{
+		err = d.runTx(ctx, fn, attempt)
+		if err == nil || !retryable(err){
+			if attempt > 0 && err == nil{
+				logging.FromContext(ctx).WithField("retries", attempt).Info("transaction succeeded after retries")
+			}
+			return
+		}
+		if attempt >= d.maxRetries{
+			atomic.AddUint64(&d.stats.Exhausted, 1)
+			logging.FromContext(ctx).WithField("retries", attempt).WithError(err).Warn("transaction failed after retries")
+			err = &m.Error{
+				Kind: m.KindConflict,
+				Code: m.CodeConcurrentUpdate,
+				Message: "concurrent update, try again",
+				Err: err,
+			}
+			return
+		}
+		atomic.AddUint64(&d.stats.Retries, 1)
+		logging.FromContext(ctx).WithField("attempt", attempt).WithError(err).Trace("retrying transaction")
+		timer := time.NewTimer(backoff(attempt))
+		select{
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (d *dbClient) runTx(ctx context.Context, fn func(tx *sqlx.Tx) error, attempt int) (err error){
+	ctx, span := tracing.Start(ctx, "postgres transaction", trace.WithAttributes(attemptAttribute.Int(attempt)))
+	defer tracing.End(span, &err)
+	tx, err := d.begin(ctx)
+	if err != nil{
+		return
+	}
+	_, err = tx.ExecContext(ctx, SetIsolationSerializable)
+	if err != nil{
+		return rollAndErr(tx, err)
+	}
+	err = fn(tx)
+	if err != nil{
+		return rollAndErr(tx, err)
+	}
+	err = tx.Commit()
+	countSQLError(err)
+	return
+}
+
+// countSQLError counts err if it was returned by Postgres.
+func countSQLError(err error){
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr){
+		metrics.SQLErrors.WithLabelValues(pgErr.SQLState()).Inc()
+	}
+}
+
+// begin opens a transaction. Failing to open one means the database can't be reached.
+func (d *dbClient) begin(ctx context.Context) (tx *sqlx.Tx, err error){
+	tx, err = d.db.BeginTxx(ctx, nil)
+	if err != nil{
+		err = m.NewUnavailableError(m.CodeDatabaseUnavailable, "database is unavailable", err)
+	}
+	return
+}
+
+// rollAndErr rolls tx back and returns err. A failed rollback is only logged, err tells more about the cause.
+func rollAndErr(tx *sqlx.Tx, err error) error{
+	log.Trace("Rollback")
+	metrics.Rollbacks.Inc()
+	countSQLError(err)
+	errRoll := tx.Rollback()
+	if errRoll != nil{
+		log.Warn(errRoll)
+	}
+	return err
+}
+
+func (d *dbClient) RetryStats() RetryStats{
+	return RetryStats{
+		Retries: atomic.LoadUint64(&d.stats.Retries),
+		Exhausted: atomic.LoadUint64(&d.stats.Exhausted),
+	}
+}
+
+var(
+	retriesDesc = prometheus.NewDesc("jobber_postgres_tx_retries_total",
+		"Reruns of transactions after serialization failures and deadlocks.", nil, nil)
+	exhaustedDesc = prometheus.NewDesc("jobber_postgres_tx_retries_exhausted_total",
+		"Transactions which still failed after the last rerun.", nil, nil)
+)
+
+var _ prometheus.Collector = (*dbClient)(nil)
+
+// Describe and Collect export the pool stats and RetryStats of the client to Prometheus. They aren't
+// a part of DbClient, so other clients don't need to export metrics.
+func (d *dbClient) Describe(ch chan<- *prometheus.Desc){
+	d.pool.Describe(ch)
+	ch <- retriesDesc
+	ch <- exhaustedDesc
+}
+
+func (d *dbClient) Collect(ch chan<- prometheus.Metric){
+	d.pool.Collect(ch)
+	stats := d.RetryStats()
+	ch <- prometheus.MustNewConstMetric(retriesDesc, prometheus.CounterValue, float64(stats.Retries))
+	ch <- prometheus.MustNewConstMetric(exhaustedDesc, prometheus.CounterValue, float64(stats.Exhausted))
+}
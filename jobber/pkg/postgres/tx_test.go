@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx"
+)
+
+func TestRetryable(t *testing.T){
+	cases := []struct{
+		Err       error
+		Retryable bool
+	}{
+		{Err: pgx.PgError{Code: serializationFailure}, Retryable: true},
+		{Err: fmt.Errorf("update balance: %w", pgx.PgError{Code: deadlockDetected}), Retryable: true},
+		{Err: pgx.PgError{Code: "23505"}, Retryable: false},
+		{Err: errors.New("40001"), Retryable: false},
+	}
+	for num, c := range cases{
+		if retryable(c.Err) != c.Retryable{
+			t.Errorf("[%d] unexpected retryable for %v, expected: %v", num, c.Err, c.Retryable)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T){
+	for attempt := 0; attempt < 70; attempt++{
+		delay := backoff(attempt)
+		if delay <= 0 || delay > retryMaxDelay{
+			t.Errorf("[%d] delay out of range: %v", attempt, delay)
+		}
+		if attempt < 5 && delay > retryBaseDelay << uint(attempt){
+			t.Errorf("[%d] delay grows too fast: %v", attempt, delay)
+		}
+	}
+}
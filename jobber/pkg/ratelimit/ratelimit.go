@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/fedorkolmykow/avitojob/pkg/config"
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+	"github.com/fedorkolmykow/avitojob/pkg/redis"
+)
+
+// Buckets of a route. Client is shared by all requests of an API client, User by all requests to a user.
+const(
+	BucketClient = "client"
+	BucketUser = "user"
+)
+
+type store interface{
+	TakeTokens(ctx context.Context, buckets []redis.Bucket) (allowed bool, tokens []float64, err error)
+}
+
+// Result tells whether a request is let in. Limit, Remaining and Reset describe Bucket, the bucket
+// closest to running out, which is the one that denied a request. Limit is zero when nothing limits the route.
+type Result struct{
+	Allowed bool
+	Bucket string
+	Limit int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a denied request can be let in
+	RetryAfter time.Duration
+}
+
+// Limiter limits requests by token buckets kept in Redis, so instances share them.
+type Limiter struct{
+	store store
+	def config.RouteLimit
+	routes map[string]config.RouteLimit
+	failOpen bool
+}
+
+func (l *Limiter) limits(route string) config.RouteLimit{
+	if limit, ok := l.routes[route]; ok{
+		return limit
+	}
+	return l.def
+}
+
+// Take takes a token of the route from the bucket of the client and, if userID isn't empty,
+// from the bucket of the user. A request is let in only when both have one. When Redis fails
+// the request is let in if the limiter fails open, otherwise the error is unavailable.
+func (l *Limiter) Take(ctx context.Context, route string, client string, userID string) (*Result, error){
+	limits := l.limits(route)
+	var names []string
+	var buckets []redis.Bucket
+	if limits.Client.Rate > 0{
+		names = append(names, BucketClient)
+		buckets = append(buckets, redis.Bucket{
+			Key: "RateLimit:" + route + ":client:" + client, Rate: limits.Client.Rate, Burst: limits.Client.Burst})
+	}
+	if limits.User.Rate > 0 && userID != ""{
+		names = append(names, BucketUser)
+		buckets = append(buckets, redis.Bucket{
+			Key: "RateLimit:" + route + ":user:" + userID, Rate: limits.User.Rate, Burst: limits.User.Burst})
+	}
+	if len(buckets) == 0{
+		return &Result{Allowed: true}, nil
+	}
+	allowed, tokens, err := l.store.TakeTokens(ctx, buckets)
+	if err != nil{
+		if l.failOpen{
+			logging.FromContext(ctx).WithError(err).Warn("rate limits aren't checked")
+			return &Result{Allowed: true}, nil
+		}
+		return nil, m.NewUnavailableError(m.CodeRateLimiterUnavailable, "rate limits can't be checked", err)
+	}
+	var res *Result
+	for i, b := range buckets{
+		r := &Result{
+			Allowed: allowed,
+			Bucket: names[i],
+			Limit: b.Burst,
+			Remaining: int(math.Max(0, math.Floor(tokens[i]))),
+			Reset: seconds((float64(b.Burst) - tokens[i]) / b.Rate),
+		}
+		if !allowed && tokens[i] < 1{
+			r.RetryAfter = seconds((1 - tokens[i]) / b.Rate)
+		}
+		if res == nil || r.RetryAfter > res.RetryAfter ||
+			r.RetryAfter == res.RetryAfter && r.Remaining < res.Remaining{
+			res = r
+		}
+	}
+	if !allowed{
+		logging.FromContext(ctx).WithFields(log.Fields{"bucket": res.Bucket, "retry_after": res.RetryAfter.String()}).
+			Warn("request is rate limited")
+	}
+	return res, nil
+}
+
+func seconds(s float64) time.Duration{
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
+
+// New makes the limiter of cfg keeping its buckets in store, the Redis client.
+func New(store store, cfg config.RateLimit) *Limiter{
+	return &Limiter{store: store, def: cfg.Default, routes: cfg.Routes, failOpen: cfg.FailOpen}
+}
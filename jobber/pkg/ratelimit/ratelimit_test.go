@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fedorkolmykow/avitojob/pkg/config"
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+	"github.com/fedorkolmykow/avitojob/pkg/redis"
+)
+
+// fakeStore answers with allowed and tokens, remembering the buckets it was asked for.
+type fakeStore struct{
+	allowed bool
+	tokens  []float64
+	err     error
+	buckets []redis.Bucket
+}
+
+func (s *fakeStore) TakeTokens(ctx context.Context, buckets []redis.Bucket) (bool, []float64, error){
+	s.buckets = buckets
+	return s.allowed, s.tokens, s.err
+}
+
+func TestTake(t *testing.T){
+	cfg := config.RateLimit{
+		Default: config.RouteLimit{Client: config.Limit{Rate: 10, Burst: 20}},
+		Routes: map[string]config.RouteLimit{
+			"transfer": {Client: config.Limit{Rate: 10, Burst: 20}, User: config.Limit{Rate: 1, Burst: 5}},
+			"metrics": {},
+		},
+	}
+	cases := []struct{
+		Route   string
+		UserID  string
+		Store   *fakeStore
+		Buckets []redis.Bucket
+		Result  *Result
+	}{
+		{
+			Route: "transfer", UserID: "7",
+			Store: &fakeStore{allowed: true, tokens: []float64{15.5, 3}},
+			Buckets: []redis.Bucket{
+				{Key: "RateLimit:transfer:client:shop", Rate: 10, Burst: 20},
+				{Key: "RateLimit:transfer:user:7", Rate: 1, Burst: 5},
+			},
+			Result: &Result{Allowed: true, Bucket: BucketUser, Limit: 5, Remaining: 3, Reset: 2*time.Second},
+		},
+		{
+			Route: "transfer", UserID: "7",
+			Store: &fakeStore{allowed: false, tokens: []float64{12, 0.5}},
+			Buckets: []redis.Bucket{
+				{Key: "RateLimit:transfer:client:shop", Rate: 10, Burst: 20},
+				{Key: "RateLimit:transfer:user:7", Rate: 1, Burst: 5},
+			},
+			Result: &Result{Bucket: BucketUser, Limit: 5, Remaining: 0, Reset: 4500*time.Millisecond, RetryAfter: 500*time.Millisecond},
+		},
+		{
+			// routes without a user take only from the client bucket of the default limits
+			Route: "getBalances",
+			Store: &fakeStore{allowed: true, tokens: []float64{0}},
+			Buckets: []redis.Bucket{{Key: "RateLimit:getBalances:client:shop", Rate: 10, Burst: 20}},
+			Result: &Result{Allowed: true, Bucket: BucketClient, Limit: 20, Remaining: 0, Reset: 2*time.Second},
+		},
+		{
+			Route: "metrics",
+			Store: &fakeStore{},
+			Result: &Result{Allowed: true},
+		},
+	}
+	for num, c := range cases{
+		res, err := New(c.Store, cfg).Take(context.Background(), c.Route, "shop", c.UserID)
+		if err != nil{
+			t.Errorf("[%d] unexpected error: %v", num, err)
+			continue
+		}
+		if !reflect.DeepEqual(c.Store.buckets, c.Buckets){
+			t.Errorf("[%d] unexpected buckets: %+v, expected: %+v", num, c.Store.buckets, c.Buckets)
+		}
+		if !reflect.DeepEqual(res, c.Result){
+			t.Errorf("[%d] unexpected result: %+v, expected: %+v", num, res, c.Result)
+		}
+	}
+
+	store := &fakeStore{err: errors.New("connection refused")}
+	res, err := New(store, cfg).Take(context.Background(), "transfer", "shop", "7")
+	if err == nil || m.KindOf(err) != m.KindUnavailable{
+		t.Errorf("unexpected result of a failed store: %+v, %v", res, err)
+	}
+	cfg.FailOpen = true
+	res, err = New(store, cfg).Take(context.Background(), "transfer", "shop", "7")
+	if err != nil || !res.Allowed{
+		t.Errorf("failed store doesn't let requests in: %+v, %v", res, err)
+	}
+}
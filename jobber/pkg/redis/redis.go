@@ -1,17 +1,29 @@
 package redis
 
 import (
+	"context"
 	"errors"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fedorkolmykow/avitojob/pkg/config"
+	"github.com/fedorkolmykow/avitojob/pkg/tracing"
 )
 
 type DbClient interface {
-    Get(key string) (value string, err error)
-    Set(key string, value string) (err error)
-    Delete(key string) (err error)
+    Get(ctx context.Context, key string) (value string, err error)
+    Set(ctx context.Context, key string, value string) (err error)
+	SetNoExpiry(ctx context.Context, key string, value string) (err error)
+    Delete(ctx context.Context, key string) (err error)
+    GetField(ctx context.Context, key string, field string) (value string, err error)
+    SetFields(ctx context.Context, key string, fields map[string]string) (err error)
+	TakeTokens(ctx context.Context, buckets []Bucket) (allowed bool, tokens []float64, err error)
+	Ping(ctx context.Context) (err error)
 	Shutdown() error
 }
 
@@ -19,10 +31,28 @@ type db struct {
 	pool *redis.Pool
 }
 
-func (d *db) Get(key string) (value string, err error){
-	conn := d.pool.Get()
+// do runs the command on conn, giving up at the deadline of ctx.
+func do(ctx context.Context, conn redis.Conn, cmd string, args ...interface{}) (reply interface{}, err error){
+	_, span := tracing.Start(ctx, "redis " + cmd, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperationKey.String(cmd)))
+	defer tracing.End(span, &err)
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return conn.Do(cmd, args...)
+	}
+	return redis.DoWithTimeout(conn, time.Until(deadline), cmd, args...)
+}
+
+func (d *db) Get(ctx context.Context, key string) (value string, err error){
+	conn, err := d.pool.GetContext(ctx)
+	if err != nil {
+		return
+	}
 	defer conn.Close()
-	value, err = redis.String(conn.Do("GET", key))
+	value, err = redis.String(do(ctx, conn, "GET", key))
 	if err != nil {
 		return
 	} else if value == "" {
@@ -32,29 +62,179 @@ func (d *db) Get(key string) (value string, err error){
 	return
 }
 
-func (d *db) Set(key string, value string) (err error){
-	conn := d.pool.Get()
+func (d *db) Set(ctx context.Context, key string, value string) (err error){
+	conn, err := d.pool.GetContext(ctx)
+	if err != nil {
+		return
+	}
 	defer conn.Close()
-	_, err = conn.Do("SET", key, value)
+	_, err = do(ctx, conn, "SET", key, value)
 	if err != nil {
 		return
 	}
 	y, m, day := time.Now().Date()
 	untilMorrow := time.Until(time.Date(y, m, day+1, 0,0,0,0, time.Local))
-	_, err = conn.Do("EXPIRE", key, untilMorrow.Seconds())
+	_, err = do(ctx, conn, "EXPIRE", key, untilMorrow.Seconds())
+	if err != nil {
+		return
+	}
+	return
+}
+
+// SetNoExpiry sets key to value, which is kept until it is overwritten.
+func (d *db) SetNoExpiry(ctx context.Context, key string, value string) (err error){
+	conn, err := d.pool.GetContext(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, err = do(ctx, conn, "SET", key, value)
+	return
+}
+
+func (d *db) Delete(ctx context.Context, key string) (err error){
+	conn, err := d.pool.GetContext(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, err = do(ctx, conn, "DEL", key)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (d *db) GetField(ctx context.Context, key string, field string) (value string, err error){
+	conn, err := d.pool.GetContext(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	value, err = redis.String(do(ctx, conn, "HGET", key, field))
+	if err != nil {
+		return
+	} else if value == "" {
+		err = errors.New("empty value")
+		return
+	}
+	return
+}
+
+// SetFields replaces the hash at key with fields in one transaction, so readers never see a partial hash.
+func (d *db) SetFields(ctx context.Context, key string, fields map[string]string) (err error){
+	if len(fields) == 0 {
+		return errors.New("no fields to set")
+	}
+	conn, err := d.pool.GetContext(ctx)
 	if err != nil {
 		return
 	}
+	defer conn.Close()
+	err = conn.Send("MULTI")
+	if err != nil {
+		return
+	}
+	err = conn.Send("DEL", key)
+	if err != nil {
+		return
+	}
+	err = conn.Send("HSET", redis.Args{}.Add(key).AddFlat(fields)...)
+	if err != nil {
+		return
+	}
+	_, err = do(ctx, conn, "EXEC")
 	return
 }
 
-func (d *db) Delete(key string) (err error){
-	conn := d.pool.Get()
+// Bucket is a token bucket at Key refilled by Rate tokens a second up to Burst.
+type Bucket struct{
+	Key string
+	Rate float64
+	Burst int
+}
+
+// takeTokens refills the buckets of KEYS by the time passed, then takes a token from each of them
+// if all have one, or from none. ARGV holds the rate and the burst of every bucket. The clock
+// of Redis is used, so instances with skewed clocks share buckets fairly.
+const takeTokens = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local tokens = {}
+local allowed = 1
+for i, key in ipairs(KEYS) do
+	local rate, burst = tonumber(ARGV[2*i-1]), tonumber(ARGV[2*i])
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local n = tonumber(state[1]) or burst
+	local ts = tonumber(state[2]) or now
+	n = math.min(burst, n + math.max(0, now - ts) * rate)
+	if n < 1 then
+		allowed = 0
+	end
+	tokens[i] = n
+end
+local result = {allowed}
+for i, key in ipairs(KEYS) do
+	local rate, burst = tonumber(ARGV[2*i-1]), tonumber(ARGV[2*i])
+	if allowed == 1 then
+		tokens[i] = tokens[i] - 1
+	end
+	redis.call('HMSET', key, 'tokens', tokens[i], 'ts', now)
+	redis.call('PEXPIRE', key, math.ceil(burst / rate * 1000) + 1000)
+	result[i+1] = tostring(tokens[i])
+end
+return result
+`
+
+var takeTokensHash = redis.NewScript(0, takeTokens).Hash()
+
+// TakeTokens takes a token from every bucket atomically, or from none if one of them is empty.
+// It returns the tokens left in the buckets.
+func (d *db) TakeTokens(ctx context.Context, buckets []Bucket) (allowed bool, tokens []float64, err error){
+	conn, err := d.pool.GetContext(ctx)
+	if err != nil {
+		return
+	}
 	defer conn.Close()
-	_, err = conn.Do("DEL", key)
+	args := redis.Args{}.Add(len(buckets))
+	for _, b := range buckets {
+		args = args.Add(b.Key)
+	}
+	for _, b := range buckets {
+		args = args.Add(strconv.FormatFloat(b.Rate, 'g', -1, 64), b.Burst)
+	}
+	reply, err := redis.Values(do(ctx, conn, "EVALSHA", append(redis.Args{takeTokensHash}, args...)...))
+	if e, ok := err.(redis.Error); ok && strings.HasPrefix(string(e), "NOSCRIPT") {
+		reply, err = redis.Values(do(ctx, conn, "EVAL", append(redis.Args{takeTokens}, args...)...))
+	}
+	if err != nil {
+		return
+	}
+	if len(reply) != len(buckets) + 1 {
+		err = errors.New("unexpected reply of the token bucket script")
+		return
+	}
+	taken, err := redis.Int(reply[0], nil)
+	if err != nil {
+		return
+	}
+	tokens = make([]float64, len(buckets))
+	for i := range buckets {
+		tokens[i], err = redis.Float64(reply[i+1], nil)
+		if err != nil {
+			return
+		}
+	}
+	return taken == 1, tokens, nil
+}
+
+func (d *db) Ping(ctx context.Context) (err error){
+	conn, err := d.pool.GetContext(ctx)
 	if err != nil {
 		return
 	}
+	defer conn.Close()
+	_, err = do(ctx, conn, "PING")
 	return
 }
 
@@ -63,14 +243,15 @@ func (d *db) Shutdown() error{
 }
 
 // NewDb returns a new Db instance.
-func NewDb() DbClient {
+func NewDb(cfg config.Redis) DbClient {
 	pool := &redis.Pool{
-		MaxIdle:     5,
-		IdleTimeout: 240 * time.Second,
+		MaxIdle:     cfg.MaxIdle,
+		MaxActive:   cfg.MaxActive,
+		IdleTimeout: cfg.IdleTimeout.Std(),
 		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp", os.Getenv("REDIS_URL"))
+			return redis.Dial("tcp", cfg.URL)
 		},
 	}
 
 	return &db{pool: pool}
-}
\ No newline at end of file
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
+	"github.com/fedorkolmykow/avitojob/pkg/metrics"
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+	"github.com/fedorkolmykow/avitojob/pkg/tracing"
+)
+
+// RateProvider quotes currencies against m.BaseCurrency: Rate returns how many units
+// of currency are given for one unit of the base currency.
+type RateProvider interface{
+	Rate(ctx context.Context, currency string) (rate float64, err error)
+}
+
+// RateTable is implemented by providers able to list all their rates at once.
+type RateTable interface{
+	Rates(ctx context.Context) (rates map[string]float64, err error)
+}
+
+func ratesKey(date time.Time) string{
+	return "Rates:" + date.In(time.Local).Format(m.RateDateLayout)
+}
+
+func sameDay(a time.Time, b time.Time) bool{
+	return a.In(time.Local).Format(m.RateDateLayout) == b.In(time.Local).Format(m.RateDateLayout)
+}
+
+func lookupRate(rates map[string]float64, currency string) (rate float64, err error){
+	if currency == m.BaseCurrency{
+		return 1, nil
+	}
+	rate, ok := rates[currency]
+	if !ok || rate <= 0{
+		err = m.NewUnsupportedCurrencyError(currency)
+	}
+	return
+}
+
+type fixedRates map[string]float64
+
+func (r fixedRates) Rate(ctx context.Context, currency string) (rate float64, err error){
+	return lookupRate(r, currency)
+}
+
+func (r fixedRates) Rates(ctx context.Context) (rates map[string]float64, err error){
+	rates = map[string]float64{}
+	for cur, rate := range r{
+		rates[cur] = rate
+	}
+	return
+}
+
+// NewFixedRateProvider returns a provider serving rates from the given table.
+func NewFixedRateProvider(rates map[string]float64) RateProvider{
+	table := fixedRates{}
+	for cur, rate := range rates{
+		table[cur] = rate
+	}
+	return table
+}
+
+// NewFileRateProvider reads rates once from a JSON file in the format of m.Rate.
+func NewFileRateProvider(path string) (RateProvider, error){
+	body, err := ioutil.ReadFile(path)
+	if err != nil{
+		return nil, err
+	}
+	r := &m.Rate{}
+	err = r.UnmarshalJSON(body)
+	if err != nil{
+		return nil, err
+	}
+	if r.Base != "" && r.Base != m.BaseCurrency{
+		return nil, errors.New("rates file is quoted against " + r.Base + ", expected " + m.BaseCurrency)
+	}
+	return NewFixedRateProvider(r.Rates), nil
+}
+
+type httpRates struct{
+	url string
+	client *http.Client
+}
+
+func (r *httpRates) Rate(ctx context.Context, currency string) (rate float64, err error){
+	if currency == m.BaseCurrency{
+		return 1, nil
+	}
+	rates, err := r.fetch(ctx, currency)
+	if err != nil{
+		return
+	}
+	return lookupRate(rates.Rates, currency)
+}
+
+// Rates asks for the url without a currency, which lists all rates of the service.
+func (r *httpRates) Rates(ctx context.Context) (rates map[string]float64, err error){
+	resp, err := r.fetch(ctx, "")
+	if err != nil{
+		return
+	}
+	return resp.Rates, nil
+}
+
+func (r *httpRates) fetch(ctx context.Context, currency string) (rates *m.Rate, err error){
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url + currency, nil)
+	if err != nil{
+		return
+	}
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	result := metrics.ResultOK
+	if err != nil || resp.StatusCode != http.StatusOK{
+		result = metrics.ResultError
+	}
+	metrics.RateProviderDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	if err != nil{
+		return
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil{
+		return
+	}
+	if resp.StatusCode != http.StatusOK{
+		err = fmt.Errorf("rates service answered %s", resp.Status)
+		return
+	}
+	rates = &m.Rate{}
+	err = rates.UnmarshalJSON(body)
+	if err != nil{
+		rates = nil
+	}
+	return
+}
+
+// NewHTTPRateProvider returns a provider asking url+currency for every rate.
+// Requests taking longer than timeout fail.
+func NewHTTPRateProvider(url string, timeout time.Duration) RateProvider{
+	return &httpRates{
+		url: url,
+		// the transport traces the calls and sends the traceparent header
+		client: &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}
+}
+
+// cacheAttribute tells whether a rate was found in the cash.
+const cacheAttribute = attribute.Key("rates.cache")
+
+// cachedRates reads rates from the table of the day saved by RateRefresher. Rates missing there
+// are asked from the provider and kept in the cash until the end of the day. The last known rate of
+// every currency is also kept in memory and in the cash without expiry, and is served while the
+// provider is down, also after a restart and by other instances.
+type cachedRates struct{
+	cash cashClient
+	provider RateProvider
+	mu sync.Mutex
+	stale map[string]float64
+}
+
+func (r *cachedRates) Rate(ctx context.Context, currency string) (rate float64, err error){
+	if currency == m.BaseCurrency{
+		return 1, nil
+	}
+	ctx, span := tracing.Start(ctx, "rates.Rate", trace.WithAttributes(attribute.String("currency", currency)))
+	defer tracing.End(span, &err)
+	rate, err = r.tableRate(ctx, currency, time.Now())
+	if err == nil{
+		logging.FromContext(ctx).WithField("currency", currency).Trace("read rate from rate table")
+		span.SetAttributes(cacheAttribute.String(metrics.ResultHit))
+		metrics.RateLookups.WithLabelValues(metrics.ResultHit).Inc()
+		r.remember(ctx, currency, rate)
+		return
+	}
+	strRate, err := r.cash.Get(ctx, "Rate:0:" + currency)
+	if err == nil{
+		rate, err = strconv.ParseFloat(strRate, 64)
+		if err == nil && rate > 0{
+			logging.FromContext(ctx).WithField("currency", currency).Trace("read rate from cash")
+			span.SetAttributes(cacheAttribute.String(metrics.ResultHit))
+			metrics.RateLookups.WithLabelValues(metrics.ResultHit).Inc()
+			r.remember(ctx, currency, rate)
+			return
+		}
+	}
+	logging.FromContext(ctx).WithField("currency", currency).WithError(err).Trace("rate isn't cached")
+	span.SetAttributes(cacheAttribute.String(metrics.ResultMiss))
+	metrics.RateLookups.WithLabelValues(metrics.ResultMiss).Inc()
+	rate, err = r.provider.Rate(ctx, currency)
+	if err != nil{
+		if errors.Is(err, m.ErrUnsupportedCurrency){
+			return
+		}
+		stale, ok := r.last(ctx, currency)
+		if !ok{
+			err = m.NewUnavailableError(m.CodeRatesUnavailable, "exchange rates are unavailable", err)
+			return
+		}
+		logging.FromContext(ctx).WithField("currency", currency).WithError(err).Warn("rates are unavailable, using the last known rate")
+		return stale, nil
+	}
+	r.remember(ctx, currency, rate)
+	e := r.cash.Set(ctx, "Rate:0:" + currency, strconv.FormatFloat(rate, 'e', -1, 64))
+	if e != nil{
+		logging.FromContext(ctx).WithError(e).Warn("failed to cache rate")
+	}
+	return
+}
+
+// RateAt returns the rate of the day of date. Rates of past days are only known
+// from the tables saved by RateRefresher. A zero date means the current rate.
+func (r *cachedRates) RateAt(ctx context.Context, currency string, date time.Time) (rate float64, err error){
+	if currency == m.BaseCurrency{
+		return 1, nil
+	}
+	if date.IsZero() || sameDay(date, time.Now()){
+		return r.Rate(ctx, currency)
+	}
+	rate, err = r.tableRate(ctx, currency, date)
+	if err != nil{
+		logging.FromContext(ctx).WithField("currency", currency).WithError(err).Trace("no rate table")
+		err = m.NewNotFoundError(m.CodeRateNotFound,
+			"no " + currency + " rate for " + date.In(time.Local).Format(m.RateDateLayout))
+	}
+	return
+}
+
+func (r *cachedRates) tableRate(ctx context.Context, currency string, date time.Time) (rate float64, err error){
+	strRate, err := r.cash.GetField(ctx, ratesKey(date), currency)
+	if err != nil{
+		return
+	}
+	rate, err = strconv.ParseFloat(strRate, 64)
+	if err == nil && rate <= 0{
+		err = errors.New("invalid rate " + strRate)
+	}
+	return
+}
+
+// lastRateKey holds the last known rate of a currency in the cash.
+func lastRateKey(currency string) string{
+	return "Rate:last:" + currency
+}
+
+// remember keeps the last known rate, it is written to the cash only when it changes.
+func (r *cachedRates) remember(ctx context.Context, currency string, rate float64){
+	r.mu.Lock()
+	changed := r.stale[currency] != rate
+	r.stale[currency] = rate
+	r.mu.Unlock()
+	if !changed{
+		return
+	}
+	err := r.cash.SetNoExpiry(ctx, lastRateKey(currency), strconv.FormatFloat(rate, 'e', -1, 64))
+	if err != nil{
+		logging.FromContext(ctx).WithError(err).Warn("failed to save the last known rate")
+	}
+}
+
+// last returns the last known rate, from memory or, after a restart, from the cash.
+func (r *cachedRates) last(ctx context.Context, currency string) (rate float64, ok bool){
+	r.mu.Lock()
+	rate, ok = r.stale[currency]
+	r.mu.Unlock()
+	if ok{
+		return
+	}
+	strRate, err := r.cash.Get(ctx, lastRateKey(currency))
+	if err != nil{
+		return 0, false
+	}
+	rate, err = strconv.ParseFloat(strRate, 64)
+	return rate, err == nil && rate > 0
+}
+
+func newCachedRates(cash cashClient, provider RateProvider) *cachedRates{
+	return &cachedRates{
+		cash: cash,
+		provider: provider,
+		stale: map[string]float64{},
+	}
+}
+
+// RateRefresher saves the whole rate table of the provider to the cash, keyed by the day,
+// once in a period. Tables of past days are kept to convert at historical rates.
+type RateRefresher struct{
+	cash cashClient
+	provider RateTable
+	period time.Duration
+}
+
+// Refresh saves the current rate table. The table replaces the table of the day at once.
+func (r *RateRefresher) Refresh(ctx context.Context) error{
+	rates, err := r.provider.Rates(ctx)
+	if err != nil{
+		return err
+	}
+	fields := map[string]string{}
+	for cur, rate := range rates{
+		if rate > 0{
+			fields[cur] = strconv.FormatFloat(rate, 'e', -1, 64)
+		}
+	}
+	err = r.cash.SetFields(ctx, ratesKey(time.Now()), fields)
+	if err != nil{
+		return err
+	}
+	log.Trace("saved " + strconv.Itoa(len(fields)) + " rates")
+	return nil
+}
+
+// Run refreshes the rates at once and then every period until ctx is done.
+func (r *RateRefresher) Run(ctx context.Context){
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+	for{
+		err := r.Refresh(ctx)
+		if err != nil{
+			log.Warn("failed to refresh rates: ", err)
+		}
+		select{
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// NewRateRefresher fails if the provider can't list its rates.
+func NewRateRefresher(cash cashClient, provider RateProvider, period time.Duration) (*RateRefresher, error){
+	table, ok := provider.(RateTable)
+	if !ok{
+		return nil, errors.New("rate provider can't list its rates")
+	}
+	if period <= 0{
+		return nil, errors.New("rate refresh period must be positive")
+	}
+	return &RateRefresher{
+		cash: cash,
+		provider: table,
+		period: period,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	m "github.com/fedorkolmykow/avitojob/pkg/models"
+)
+
+type reconcileClient interface{
+	Reconcile(ctx context.Context, repair bool) (Resp *m.ReconciliationReport, err error)
+}
+
+// Reconciler checks wallet balances against their history once in a period and saves every report
+// to dir as reconcile-<time>.json. In repair mode the missing changes are recorded as corrections.
+type Reconciler struct{
+	db reconcileClient
+	period time.Duration
+	repair bool
+	dir string
+}
+
+// Reconcile runs one check and saves its report, path is the file of the report.
+func (r *Reconciler) Reconcile(ctx context.Context) (report *m.ReconciliationReport, path string, err error){
+	report, err = r.db.Reconcile(ctx, r.repair)
+	if err != nil{
+		return
+	}
+	body, err := report.MarshalJSON()
+	if err != nil{
+		return
+	}
+	err = os.MkdirAll(r.dir, 0777)
+	if err != nil{
+		return
+	}
+	path = filepath.Join(r.dir, "reconcile-" + report.Started.UTC().Format("20060102T150405Z") + ".json")
+	err = ioutil.WriteFile(path, body, 0666)
+	return
+}
+
+// Run reconciles at once and then every period until ctx is done.
+func (r *Reconciler) Run(ctx context.Context){
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+	for{
+		report, path, err := r.Reconcile(ctx)
+		switch{
+		case err != nil:
+			log.Warn("failed to reconcile balances: ", err)
+		case len(report.Discrepancies) > 0:
+			log.Error("found " + strconv.Itoa(len(report.Discrepancies)) + " balance discrepancies, see " + path)
+		default:
+			log.Trace("balances are reconciled, see " + path)
+		}
+		select{
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewReconciler(db reconcileClient, period time.Duration, repair bool, dir string) (*Reconciler, error){
+	if period <= 0{
+		return nil, errors.New("reconciliation period must be positive")
+	}
+	return &Reconciler{
+		db: db,
+		period: period,
+		repair: repair,
+		dir: dir,
+	}, nil
+}
@@ -1,161 +1,428 @@
 package service
 
 import (
-	"io/ioutil"
-	"math"
-	"net/http"
-	"os"
-	"sort"
+	"context"
+	"encoding/base64"
+	"errors"
 	"strconv"
+	"strings"
+	"time"
 
-	log "github.com/sirupsen/logrus"
-
+	"github.com/fedorkolmykow/avitojob/pkg/logging"
 	m "github.com/fedorkolmykow/avitojob/pkg/models"
+	"github.com/fedorkolmykow/avitojob/pkg/tracing"
 
 )
 
 type Service interface {
-	ChangeBalance(Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error)
-	Transfer(Req *m.TransferReq) (Resp *m.TransferResp, err error)
-	GetBalance(Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error)
-	GetTransactions(Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error)
+	ChangeBalance(ctx context.Context, Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error)
+	Transfer(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error)
+	Reserve(ctx context.Context, Req *m.ReserveReq) (Resp *m.HoldResp, err error)
+	CaptureHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error)
+	ReleaseHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error)
+	Refund(ctx context.Context, Req *m.RefundReq) (Resp *m.RefundResp, err error)
+	GetBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error)
+	GetBalances(ctx context.Context, Req *m.GetBalancesReq) (Resp *m.GetBalancesResp, err error)
+	GetTransactions(ctx context.Context, Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error)
+	GetTransfer(ctx context.Context, Req *m.GetTransferReq) (Resp *m.Transfer, err error)
+	CheckLedger(ctx context.Context) (Resp *m.LedgerCheck, err error)
 }
 
 type dbClient interface{
-	UpdateBalance(Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error)
-	UpdateBalances(Req *m.TransferReq) (Resp *m.TransferResp, err error)
-	SelectBalance(Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error)
-	SelectTransactions(Req *m.GetTransactionsReq) (Resp *m.Transactions, err error)
+	UpdateBalance(ctx context.Context, Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error)
+	UpdateBalances(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error)
+	ReplayTransfer(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error)
+	ReserveBalance(ctx context.Context, Req *m.ReserveReq) (Resp *m.HoldResp, err error)
+	CaptureHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error)
+	ReleaseHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error)
+	RefundTransaction(ctx context.Context, Req *m.RefundReq) (Resp *m.RefundResp, err error)
+	SelectBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error)
+	SelectBalances(ctx context.Context, Req *m.GetBalancesReq) (Resp *m.GetBalancesResp, err error)
+	SelectTransactions(ctx context.Context, Req *m.GetTransactionsReq) (Resp *m.Transactions, err error)
+	SelectTransfer(ctx context.Context, Req *m.GetTransferReq) (Resp *m.Transfer, err error)
+	CheckLedger(ctx context.Context) (Resp *m.LedgerCheck, err error)
 }
 
 type cashClient interface{
-	Get(key string) (value string, err error)
-	Set(key string, value string) (err error)
-	Delete(key string) (err error)
+	Get(ctx context.Context, key string) (value string, err error)
+	Set(ctx context.Context, key string, value string) (err error)
+	SetNoExpiry(ctx context.Context, key string, value string) (err error)
+	Delete(ctx context.Context, key string) (err error)
+	GetField(ctx context.Context, key string, field string) (value string, err error)
+	SetFields(ctx context.Context, key string, fields map[string]string) (err error)
 }
 
 type service struct{
 	db dbClient
-	cash cashClient
+	rates *cachedRates
+}
+
+func (s *service) ChangeBalance(ctx context.Context, Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error) {
+	ctx, span := tracing.Start(ctx, "service.ChangeBalance")
+	defer tracing.End(span, &err)
+	defaultCurrency(&Req.Currency, m.BaseCurrency)
+	err = Req.Validate()
+	if err != nil{
+		return
+	}
+	Resp, err = s.db.UpdateBalance(ctx, Req)
+	return
+}
+
+func (s *service) Transfer(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error) {
+	ctx, span := tracing.Start(ctx, "service.Transfer")
+	defer tracing.End(span, &err)
+	defaultCurrency(&Req.Currency, m.BaseCurrency)
+	defaultCurrency(&Req.TargetCurrency, Req.Currency)
+	err = Req.Validate()
+	if err != nil{
+		return
+	}
+	// a retry gets the stored response, it doesn't need the rates which may be down by now
+	Resp, err = s.db.ReplayTransfer(ctx, Req)
+	if err != nil || Resp != nil{
+		return
+	}
+	Req.Rate, err = crossRate(ctx, s.rates, Req.Currency, Req.TargetCurrency, time.Time{})
+	if err != nil{
+		return
+	}
+	Req.TargetChange, err = Req.Change.Convert(Req.Rate, Req.TargetCurrency)
+	if err != nil{
+		return
+	}
+	if Req.TargetChange <= 0{
+		err = m.NewValidationError("change", "transfer is too small to convert")
+		return
+	}
+	Resp, err = s.db.UpdateBalances(ctx, Req)
+	return
 }
 
-func (s *service) ChangeBalance(Req *m.ChangeBalanceReq) (Resp *m.ChangeBalanceResp, err error) {
+func (s *service) Reserve(ctx context.Context, Req *m.ReserveReq) (Resp *m.HoldResp, err error) {
+	ctx, span := tracing.Start(ctx, "service.Reserve")
+	defer tracing.End(span, &err)
+	defaultCurrency(&Req.Currency, m.BaseCurrency)
 	err = Req.Validate()
 	if err != nil{
 		return
 	}
-	Resp, err = s.db.UpdateBalance(Req)
+	Resp, err = s.db.ReserveBalance(ctx, Req)
 	return
 }
 
-func (s *service) Transfer(Req *m.TransferReq) (Resp *m.TransferResp, err error) {
+func (s *service) CaptureHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error) {
+	ctx, span := tracing.Start(ctx, "service.CaptureHold")
+	defer tracing.End(span, &err)
 	err = Req.Validate()
 	if err != nil{
 		return
 	}
-	Resp, err = s.db.UpdateBalances(Req)
+	Resp, err = s.db.CaptureHold(ctx, Req)
 	return
 }
 
-func (s *service) GetBalance(Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error) {
+func (s *service) ReleaseHold(ctx context.Context, Req *m.HoldReq) (Resp *m.HoldResp, err error) {
+	ctx, span := tracing.Start(ctx, "service.ReleaseHold")
+	defer tracing.End(span, &err)
 	err = Req.Validate()
 	if err != nil{
 		return
 	}
-	Resp, err = s.db.SelectBalance(Req)
+	Resp, err = s.db.ReleaseHold(ctx, Req)
+	return
+}
+
+func (s *service) Refund(ctx context.Context, Req *m.RefundReq) (Resp *m.RefundResp, err error) {
+	ctx, span := tracing.Start(ctx, "service.Refund")
+	defer tracing.End(span, &err)
+	err = Req.Validate()
+	if err != nil{
+		return
+	}
+	Resp, err = s.db.RefundTransaction(ctx, Req)
+	return
+}
+
+func (s *service) GetBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error) {
+	ctx, span := tracing.Start(ctx, "service.GetBalance")
+	defer tracing.End(span, &err)
+	defaultCurrency(&Req.Wallet, m.BaseCurrency)
+	defaultCurrency(&Req.Currency, Req.Wallet)
+	err = Req.Validate()
+	if err != nil{
+		return
+	}
+	// a past balance is converted at the rate of its day
+	if Req.RateDate.IsZero(){
+		Req.RateDate = Req.AsOf
+	}
+	Resp, err = s.db.SelectBalance(ctx, Req)
 	if err != nil {
 		return
 	}
-	if Req.Currency != ""{
+	if Req.Currency != Req.Wallet{
 		var rate float64
-		rate, err = getRate(s.cash, Req.Currency)
+		rate, err = crossRate(ctx, s.rates, Req.Wallet, Req.Currency, Req.RateDate)
 		if err != nil{
 			return
 		}
+		err = convertBalance(Resp, rate, Req.Currency)
+	}
+	return
+}
+
+func (s *service) GetBalances(ctx context.Context, Req *m.GetBalancesReq) (Resp *m.GetBalancesResp, err error) {
+	ctx, span := tracing.Start(ctx, "service.GetBalances")
+	defer tracing.End(span, &err)
+	defaultCurrency(&Req.Wallet, m.BaseCurrency)
+	defaultCurrency(&Req.Currency, Req.Wallet)
+	if Req.AsOf.IsZero(){
+		Req.AsOf = time.Now()
+	}
+	err = Req.Validate()
+	if err != nil{
+		return
+	}
+	if Req.RateDate.IsZero(){
+		Req.RateDate = Req.AsOf
+	}
+	Resp, err = s.db.SelectBalances(ctx, Req)
+	if err != nil {
+		return
+	}
+	if Req.Currency != Req.Wallet{
+		var rate float64
+		rate, err = crossRate(ctx, s.rates, Req.Wallet, Req.Currency, Req.RateDate)
+		if err != nil{
+			return
+		}
+		for i := range Resp.Balances{
+			err = convertBalance(&Resp.Balances[i], rate, Req.Currency)
+			if err != nil{
+				return
+			}
+		}
 		Resp.Currency = Req.Currency
-		Resp.Balance = Resp.Balance * rate
-	} else{
-		Resp.Currency = "RUB"
 	}
 	return
 }
 
-func (s *service) GetTransactions(Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error) {
+func convertBalance(b *m.GetBalanceResp, rate float64, currency string) (err error){
+	b.Balance, err = b.Balance.Convert(rate, currency)
+	if err != nil{
+		return
+	}
+	b.Held, err = b.Held.Convert(rate, currency)
+	if err != nil{
+		return
+	}
+	b.Available = b.Balance - b.Held
+	b.Currency = currency
+	return
+}
+
+func defaultCurrency(currency *string, def string){
+	if *currency == ""{
+		*currency = def
+	}
+}
+
+// crossRate returns how many units of to were given for one unit of from on the day of date.
+// Rates are quoted against the base currency.
+func crossRate(ctx context.Context, rates *cachedRates, from string, to string, date time.Time) (rate float64, err error){
+	if from == to{
+		return 1, nil
+	}
+	fromRate, err := rates.RateAt(ctx, from, date)
+	if err != nil{
+		return
+	}
+	toRate, err := rates.RateAt(ctx, to, date)
+	if err != nil{
+		return
+	}
+	rate = toRate / fromRate
+	return
+}
+
+func (s *service) GetTransactions(ctx context.Context, Req *m.GetTransactionsReq) (Resp *m.GetTransactionsResp, err error) {
+	ctx, span := tracing.Start(ctx, "service.GetTransactions")
+	defer tracing.End(span, &err)
 	err = Req.Validate()
 	if err != nil{
 		return
 	}
+	if Req.TransactionsOnPage == 0{
+		Req.TransactionsOnPage = m.DefaultTransactionsOnPage
+	}
+	Req.After, err = decodeCursor(Req)
+	if err != nil{
+		return
+	}
 	Resp = &m.GetTransactionsResp{}
-	log.Trace(Resp)
-	trs, err := s.db.SelectTransactions(Req)
+	trs, err := s.db.SelectTransactions(ctx, Req)
 	if err != nil{
 		return
 	}
-	sort.Sort(trs)
-	Resp.Transactions = pagination(trs.Transactions, Req.Page, Req.TransactionsOnPage)
+	Resp.Transactions = trs.Transactions
+	if Req.Currency != ""{
+		err = s.convertTransactions(ctx, Resp.Transactions, Req.Currency)
+		if err != nil{
+			return
+		}
+	}
+	if trs.HasMore && len(trs.Transactions) > 0{
+		Resp.NextCursor = encodeCursor(Req, trs.Transactions[len(trs.Transactions) - 1])
+	}
 	Resp.UserId = Req.UserId
 	return
 }
 
-func pagination(trs []m.Transaction, page int, perPage int) (tr []m.Transaction){
-
-	firsT := (page - 1) * perPage
-	if firsT > len(trs){		//if page index too big then show last page
-		pagesCount := math.Ceil(float64(len(trs))/float64(perPage))
-		firsT = (int(pagesCount) - 1) * perPage
+// convertTransactions sets Converted of the transactions at the rates of their days.
+// Transactions of days without known rates are left unconverted. The rate of every day and
+// currency of the page is looked up once.
+func (s *service) convertTransactions(ctx context.Context, trs []m.Transaction, currency string) (err error){
+	ctx, span := tracing.Start(ctx, "service.convertTransactions")
+	defer tracing.End(span, &err)
+	type dayRate struct{
+		rate float64
+		err error
 	}
-	if len(trs) - perPage < firsT{
-		perPage = len(trs)%perPage
+	rates := map[string]dayRate{}
+	for i := range trs{
+		tr := &trs[i]
+		key := tr.ChangeTime.In(time.Local).Format(m.RateDateLayout) + ":" + tr.Currency
+		r, ok := rates[key]
+		if !ok{
+			r.rate, r.err = crossRate(ctx, s.rates, tr.Currency, currency, tr.ChangeTime)
+			if errors.Is(r.err, m.ErrUnsupportedCurrency){
+				return r.err
+			}
+			rates[key] = r
+		}
+		if r.err != nil{
+			logging.FromContext(ctx).WithField("trans_id", tr.TransId).WithError(r.err).Trace("transaction left unconverted")
+			continue
+		}
+		converted, e := tr.Change.Convert(r.rate, currency)
+		if e != nil{
+			logging.FromContext(ctx).WithField("trans_id", tr.TransId).WithError(e).Trace("transaction left unconverted")
+			continue
+		}
+		tr.Converted = &converted
 	}
-	tr = make([]m.Transaction, perPage)
-	for i := 0; i < perPage; i++{
-		tr[i] = trs[firsT + i]
+	return
+}
+
+func (s *service) GetTransfer(ctx context.Context, Req *m.GetTransferReq) (Resp *m.Transfer, err error) {
+	ctx, span := tracing.Start(ctx, "service.GetTransfer")
+	defer tracing.End(span, &err)
+	err = Req.Validate()
+	if err != nil{
+		return
 	}
+	Resp, err = s.db.SelectTransfer(ctx, Req)
 	return
 }
 
-func getRate(cash cashClient, currency string) (rate float64, err error){
-	var strRate string
-	strRate, err = cash.Get("Rate:0:" + currency)
-	if err == nil{
-		rate, err = strconv.ParseFloat(strRate, 64)
-		if err == nil {
-			log.Trace("read rate from cash")
-			return
+// CheckLedger reports the currencies and entries of the ledger whose postings don't sum to zero.
+func (s *service) CheckLedger(ctx context.Context) (Resp *m.LedgerCheck, err error) {
+	ctx, span := tracing.Start(ctx, "service.CheckLedger")
+	defer tracing.End(span, &err)
+	return s.db.CheckLedger(ctx)
+}
+
+// Cursors are opaque to clients. They carry the sorting and the filter (user and period) they were
+// issued for, so a cursor can't be reused with another sorting or filter and skip rows.
+const(
+	changeCursor = "c"
+	timeCursor = "t"
+	idCursor = "i"
+)
+
+func cursorKind(Req *m.GetTransactionsReq) string{
+	switch{
+	case Req.ChangeSort:
+		return changeCursor
+	case Req.TimeSort:
+		return timeCursor
+	}
+	return idCursor
+}
+
+// cursorFilter encodes the user and the period of the request, a zero bound is empty.
+func cursorFilter(Req *m.GetTransactionsReq) string{
+	bound := func(t time.Time) string{
+		if t.IsZero(){
+			return ""
 		}
-		log.Trace(err)
-	} else{
-		log.Trace(err)
-		err = nil
+		return strconv.FormatInt(t.UnixNano(), 10)
 	}
-	var r *http.Response
-	var body []byte
-	r, err = http.Get(os.Getenv("CURRENCY_URL")+currency)
-	if err != nil {
+	return strconv.Itoa(Req.UserId) + ":" + bound(Req.From) + ":" + bound(Req.To)
+}
+
+func encodeCursor(Req *m.GetTransactionsReq, last m.Transaction) string{
+	raw := cursorKind(Req) + ":" + cursorFilter(Req) + ":" + strconv.Itoa(last.TransId)
+	switch{
+	case Req.ChangeSort:
+		raw += ":" + strconv.FormatInt(int64(last.Change), 10)
+	case Req.TimeSort:
+		raw += ":" + strconv.FormatInt(last.ChangeTime.UnixNano(), 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(Req *m.GetTransactionsReq) (cursor *m.TransactionsCursor, err error){
+	if Req.Cursor == ""{
 		return
 	}
-	body, err = ioutil.ReadAll(r.Body)
-	if err != nil {
+	errInvalid := m.NewValidationError("cursor", "invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(Req.Cursor)
+	if err != nil{
+		err = errInvalid
 		return
 	}
-	resp := &m.Rate{}
-	err = resp.UnmarshalJSON(body)
-	if err != nil {
+	// kind, user, from, to, trans_id and the sort value
+	parts := strings.Split(string(raw), ":")
+	if parts[0] != cursorKind(Req){
+		err = m.NewValidationError("cursor", "cursor was issued for another sorting")
 		return
 	}
-	strRate = strconv.FormatFloat(resp.Rates[currency], 'e', -1, 64)
-	err = cash.Set("Rate:0:" + currency, strRate)
-	if err != nil {
+	if (parts[0] == idCursor && len(parts) != 5) || (parts[0] != idCursor && len(parts) != 6){
+		err = errInvalid
+		return
+	}
+	if strings.Join(parts[1:4], ":") != cursorFilter(Req){
+		err = m.NewValidationError("cursor", "cursor was issued for another user or period")
+		return
+	}
+	cursor = &m.TransactionsCursor{}
+	cursor.TransId, err = strconv.Atoi(parts[4])
+	if err != nil{
+		cursor, err = nil, errInvalid
+		return
+	}
+	if parts[0] == idCursor{
+		return
+	}
+	value, err := strconv.ParseInt(parts[5], 10, 64)
+	if err != nil{
+		cursor, err = nil, errInvalid
 		return
 	}
-	rate = resp.Rates[currency]
+	if Req.ChangeSort{
+		cursor.Change = m.Money(value)
+	} else{
+		cursor.Time = time.Unix(0, value)
+	}
 	return
 }
 
-func NewService(db dbClient, cash cashClient) Service{
+func NewService(db dbClient, cash cashClient, rates RateProvider) Service{
     svc := &service{
     	db: db,
-    	cash: cash,
+    	rates: newCachedRates(cash, rates),
 	}
     return svc
 }
\ No newline at end of file
@@ -1,55 +1,549 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	m "github.com/fedorkolmykow/avitojob/pkg/models"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
-func TestPaginationSuccess(t *testing.T){
-	perPage := 3
-	page := 1
-	l := 5
-	trs := make([]m.Transaction, l)
-	for i := 0; i < l; i++{
-		trs[i].UserId = i
+// pageDb serves only SelectTransactions, other methods of dbClient panic.
+type pageDb struct{
+	dbClient
+	trs *m.Transactions
+	req *m.GetTransactionsReq
+}
+
+func (d *pageDb) SelectTransactions(ctx context.Context, Req *m.GetTransactionsReq) (Resp *m.Transactions, err error){
+	d.req = Req
+	return d.trs, nil
+}
+
+func TestCursorRoundTrip(t *testing.T){
+	reqs := []*m.GetTransactionsReq{
+		{ChangeSort: true},
+		{TimeSort: true},
+		{},
+	}
+	last := m.Transaction{
+		TransId:    42,
+		Change:     -1500,
+		ChangeTime: time.Date(2009, 11, 17, 20, 34, 58, 651387000, time.UTC),
 	}
-	tr := pagination(trs, page, perPage)
-	for i := 0; i < perPage; i++{
-		if !reflect.DeepEqual(tr[i], trs[(page - 1) * perPage+ i]){
-			t.Errorf("wrong pagination:\n%+v\n%+v\n", tr, trs)
+	for num, req := range reqs{
+		req.Cursor = encodeCursor(req, last)
+		cursor, err := decodeCursor(req)
+		if err != nil{
+			t.Errorf("[%d] unexpected error: %v", num, err)
+			continue
 		}
+		expected := &m.TransactionsCursor{TransId: last.TransId}
+		if req.ChangeSort{
+			expected.Change = last.Change
+		}
+		if req.TimeSort{
+			if !cursor.Time.Equal(last.ChangeTime){
+				t.Errorf("[%d] unexpected cursor time: %v, expected: %v", num, cursor.Time, last.ChangeTime)
+			}
+			cursor.Time = time.Time{}
+		}
+		if !reflect.DeepEqual(cursor, expected){
+			t.Errorf("[%d] unexpected cursor: %+v, expected: %+v", num, cursor, expected)
+		}
+	}
+}
+
+func TestCursorWrongSorting(t *testing.T){
+	last := m.Transaction{TransId: 42, Change: -1500}
+	req := &m.GetTransactionsReq{Cursor: encodeCursor(&m.GetTransactionsReq{ChangeSort: true}, last)}
+	_, err := decodeCursor(req)
+	if err == nil{
+		t.Error("cursor for change sorting was accepted for time sorting")
 	}
 }
 
-func TestPaginationShortPage(t *testing.T){
-	perPage := 3
-	page := 2
-	l := 5
-	trs := make([]m.Transaction, l)
-	for i := 0; i < l; i++{
-		trs[i].UserId = i
+func TestCursorWrongFilter(t *testing.T){
+	last := m.Transaction{TransId: 42, ChangeTime: time.Now()}
+	issued := &m.GetTransactionsReq{UserId: 1, TimeSort: true, From: time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)}
+	cursor := encodeCursor(issued, last)
+	reqs := []*m.GetTransactionsReq{
+		{UserId: 2, TimeSort: true, From: issued.From},
+		{UserId: 1, TimeSort: true},
+		{UserId: 1, TimeSort: true, From: issued.From, To: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)},
 	}
-	tr := pagination(trs, page, perPage)
-	for i := 0; i < len(tr); i++{
-		if !reflect.DeepEqual(tr[i], trs[(page - 1) * perPage+ i]){
-			t.Errorf("wrong pagination:\n%+v\n%+v\n", tr, trs)
+	for num, req := range reqs{
+		req.Cursor = cursor
+		_, err := decodeCursor(req)
+		if err == nil{
+			t.Errorf("[%d] cursor was accepted for another filter", num)
 		}
 	}
+	issued.Cursor = cursor
+	if _, err := decodeCursor(issued); err != nil{
+		t.Errorf("cursor wasn't accepted for its filter: %v", err)
+	}
+}
+
+func TestCursorGarbage(t *testing.T){
+	for _, cursor := range []string{"Here is error", "Yzo0Mg", "aTp4"}{
+		_, err := decodeCursor(&m.GetTransactionsReq{ChangeSort: true, Cursor: cursor})
+		if err == nil{
+			t.Errorf("invalid cursor %q was accepted", cursor)
+		}
+	}
+}
+
+func TestGetTransactionsNextCursor(t *testing.T){
+	db := &pageDb{trs: &m.Transactions{
+		Transactions: []m.Transaction{{TransId: 1}, {TransId: 2}},
+		HasMore:      true,
+	}}
+	svc := NewService(db, nil, nil)
+	resp, err := svc.GetTransactions(context.Background(), &m.GetTransactionsReq{UserId: 1, TimeSort: true})
+	if err != nil{
+		t.Fatal(err)
+	}
+	if db.req.TransactionsOnPage != m.DefaultTransactionsOnPage{
+		t.Errorf("unexpected page size: %d", db.req.TransactionsOnPage)
+	}
+	if resp.NextCursor == ""{
+		t.Fatal("no next cursor while there are more transactions")
+	}
+	resp, err = svc.GetTransactions(context.Background(), &m.GetTransactionsReq{UserId: 1, TimeSort: true, Cursor: resp.NextCursor})
+	if err != nil{
+		t.Fatal(err)
+	}
+	if db.req.After == nil || db.req.After.TransId != 2{
+		t.Errorf("next page doesn't start after the last transaction: %+v", db.req.After)
+	}
+
+	db.trs.HasMore = false
+	resp, err = svc.GetTransactions(context.Background(), &m.GetTransactionsReq{UserId: 1})
+	if err != nil{
+		t.Fatal(err)
+	}
+	if resp.NextCursor != ""{
+		t.Errorf("unexpected next cursor on the last page: %s", resp.NextCursor)
+	}
 }
 
-func TestPaginationLastPage(t *testing.T){
-	perPage := 3
-	page := 999
-	l := 6
-	trs := make([]m.Transaction, l)
-	for i := 0; i < l; i++{
-		trs[i].UserId = i
+func TestGetTransactionsPageBound(t *testing.T){
+	svc := NewService(&pageDb{trs: &m.Transactions{}}, &rateCash{}, &downRates{})
+	cases := []struct{
+		Req *m.GetTransactionsReq
+		Ok  bool
+	}{
+		{Req: &m.GetTransactionsReq{UserId: 1, Page: 501}, Ok: true},
+		{Req: &m.GetTransactionsReq{UserId: 1, Page: 502}},
+		{Req: &m.GetTransactionsReq{UserId: 1, Page: 11, TransactionsOnPage: 1000}, Ok: true},
+		{Req: &m.GetTransactionsReq{UserId: 1, Page: 12, TransactionsOnPage: 1000}},
+		{Req: &m.GetTransactionsReq{UserId: 1, Page: math.MaxInt64}},
 	}
-	tr := pagination(trs, page, perPage)
-	for i := 0; i < len(tr); i++{
-		if !reflect.DeepEqual(tr[i], trs[3 + i]){
-			t.Errorf("wrong pagination:\n%+v\n%+v\n", tr, trs)
+	for num, c := range cases{
+		_, err := svc.GetTransactions(context.Background(), c.Req)
+		if (err == nil) != c.Ok{
+			t.Errorf("[%d] unexpected error: %v", num, err)
 		}
 	}
-}
\ No newline at end of file
+}
+
+type rateCash struct{
+	rates map[string]string
+	tables map[string]map[string]string
+	// lookups counts the reads of rate tables
+	lookups int
+}
+
+func (c *rateCash) Get(ctx context.Context, key string) (value string, err error){
+	value, ok := c.rates[key]
+	if !ok{
+		err = errors.New("no value")
+	}
+	return
+}
+
+func (c *rateCash) Set(ctx context.Context, key string, value string) (err error){
+	return
+}
+
+func (c *rateCash) SetNoExpiry(ctx context.Context, key string, value string) (err error){
+	if c.rates == nil{
+		c.rates = map[string]string{}
+	}
+	c.rates[key] = value
+	return
+}
+
+func (c *rateCash) Delete(ctx context.Context, key string) (err error){
+	return
+}
+
+func (c *rateCash) GetField(ctx context.Context, key string, field string) (value string, err error){
+	c.lookups++
+	value, ok := c.tables[key][field]
+	if !ok{
+		err = errors.New("no value")
+	}
+	return
+}
+
+func (c *rateCash) SetFields(ctx context.Context, key string, fields map[string]string) (err error){
+	if c.tables == nil{
+		c.tables = map[string]map[string]string{}
+	}
+	c.tables[key] = fields
+	return
+}
+
+// transferDb records the transfer it was asked to make and keeps the responses by idempotency keys.
+type transferDb struct{
+	dbClient
+	req *m.TransferReq
+	stored map[string]*m.TransferResp
+}
+
+func (d *transferDb) UpdateBalances(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error){
+	d.req = Req
+	Resp = &m.TransferResp{Rate: Req.Rate}
+	if Req.IdempotencyKey != ""{
+		if d.stored == nil{
+			d.stored = map[string]*m.TransferResp{}
+		}
+		d.stored[Req.IdempotencyKey] = Resp
+	}
+	return
+}
+
+func (d *transferDb) ReplayTransfer(ctx context.Context, Req *m.TransferReq) (Resp *m.TransferResp, err error){
+	return d.stored[Req.IdempotencyKey], nil
+}
+
+func TestTransferConvertsCurrency(t *testing.T){
+	db := &transferDb{}
+	rates := NewFixedRateProvider(map[string]float64{"USD": 0.0125, "EUR": 0.01})
+	svc := NewService(db, &rateCash{}, rates)
+	cases := []struct{
+		Req          *m.TransferReq
+		Rate         float64
+		TargetChange m.Money
+	}{
+		{Req: &m.TransferReq{UserId: 1, TargetId: 2, Change: 10000}, Rate: 1, TargetChange: 10000},
+		{Req: &m.TransferReq{UserId: 1, TargetId: 2, Change: 10000, TargetCurrency: "USD"}, Rate: 0.0125, TargetChange: 125},
+		{Req: &m.TransferReq{UserId: 1, TargetId: 1, Change: 100, Currency: "USD", TargetCurrency: "RUB"}, Rate: 80, TargetChange: 8000},
+		{Req: &m.TransferReq{UserId: 1, TargetId: 2, Change: 100, Currency: "EUR", TargetCurrency: "USD"}, Rate: 1.25, TargetChange: 125},
+	}
+	for num, c := range cases{
+		_, err := svc.Transfer(context.Background(), c.Req)
+		if err != nil{
+			t.Errorf("[%d] unexpected error: %v", num, err)
+			continue
+		}
+		if db.req.Rate != c.Rate || db.req.TargetChange != c.TargetChange{
+			t.Errorf("[%d] unexpected conversion: rate %v, target change %d, expected: rate %v, target change %d",
+				num, db.req.Rate, db.req.TargetChange, c.Rate, c.TargetChange)
+		}
+	}
+	_, err := svc.Transfer(context.Background(), &m.TransferReq{UserId: 1, TargetId: 1, Change: 100})
+	if err == nil{
+		t.Error("transfer to the same wallet was accepted")
+	}
+}
+
+func TestTransferReplayWithoutRates(t *testing.T){
+	db := &transferDb{}
+	cash := &rateCash{rates: map[string]string{"Rate:0:USD": "0.0125"}}
+	provider := &downRates{}
+	svc := NewService(db, cash, provider)
+	req := func() *m.TransferReq{
+		return &m.TransferReq{UserId: 1, TargetId: 2, Change: 10000, TargetCurrency: "USD", IdempotencyKey: "k1"}
+	}
+	_, err := svc.Transfer(context.Background(), req())
+	if err != nil{
+		t.Fatal(err)
+	}
+	// the rates are gone, a retry is still answered with the stored response
+	delete(cash.rates, "Rate:0:USD")
+	svc = NewService(db, cash, provider)
+	db.req = nil
+	resp, err := svc.Transfer(context.Background(), req())
+	if err != nil || resp.Rate != 0.0125{
+		t.Errorf("retry wasn't replayed: %+v, %v", resp, err)
+	}
+	if db.req != nil || provider.calls != 0{
+		t.Errorf("retry was priced again: %d calls to rates", provider.calls)
+	}
+}
+
+// downRates fails every request like an unreachable rates service.
+type downRates struct{
+	calls int
+}
+
+func (r *downRates) Rate(ctx context.Context, currency string) (rate float64, err error){
+	r.calls++
+	return 0, errors.New("connection refused")
+}
+
+func TestGetBalanceStaleRate(t *testing.T){
+	db := &balanceDb{}
+	cash := &rateCash{rates: map[string]string{"Rate:0:USD": "0.0125"}}
+	provider := &downRates{}
+	svc := NewService(db, cash, provider)
+	_, err := svc.GetBalance(context.Background(), &m.GetBalanceReq{UserId: 1, Currency: "USD"})
+	if err != nil{
+		t.Fatal(err)
+	}
+	delete(cash.rates, "Rate:0:USD")
+	resp, err := svc.GetBalance(context.Background(), &m.GetBalanceReq{UserId: 1, Currency: "USD"})
+	if err != nil{
+		t.Fatalf("stale rate wasn't used: %v", err)
+	}
+	if provider.calls != 1 || resp.Balance != 125{
+		t.Errorf("unexpected balance %d after %d calls to rates", resp.Balance, provider.calls)
+	}
+	_, err = svc.GetBalance(context.Background(), &m.GetBalanceReq{UserId: 1, Currency: "EUR"})
+	if err == nil{
+		t.Error("balance was converted without a rate")
+	}
+
+	// a restarted service reads the last known rate from the cash
+	svc = NewService(db, cash, provider)
+	resp, err = svc.GetBalance(context.Background(), &m.GetBalanceReq{UserId: 1, Currency: "USD"})
+	if err != nil || resp.Balance != 125{
+		t.Errorf("last known rate wasn't read from the cash: %v, %v", resp, err)
+	}
+}
+
+// balanceDb serves a wallet with 100 rubles.
+type balanceDb struct{
+	dbClient
+}
+
+func (d *balanceDb) SelectBalance(ctx context.Context, Req *m.GetBalanceReq) (Resp *m.GetBalanceResp, err error){
+	return &m.GetBalanceResp{UserId: Req.UserId, Balance: 10000, Available: 10000, Currency: Req.Wallet}, nil
+}
+
+func TestUnsupportedCurrency(t *testing.T){
+	svc := NewService(&balanceDb{}, &rateCash{}, NewFixedRateProvider(map[string]float64{"USD": 0.0125}))
+	_, err := svc.GetBalance(context.Background(), &m.GetBalanceReq{UserId: 1, Currency: "XXX"})
+	if !errors.Is(err, m.ErrUnsupportedCurrency){
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHTTPRateProvider(t *testing.T){
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"rates":{"USD":0.0125},"base":"RUB","date":"2020-09-01"}`))
+	}))
+	defer ts.Close()
+	provider := NewHTTPRateProvider(ts.URL + "/?symbols=", time.Second)
+	rate, err := provider.Rate(context.Background(), "USD")
+	if err != nil || rate != 0.0125{
+		t.Errorf("unexpected rate %v, error: %v", rate, err)
+	}
+	_, err = provider.Rate(context.Background(), "EUR")
+	if !errors.Is(err, m.ErrUnsupportedCurrency){
+		t.Errorf("unexpected error for a missing rate: %v", err)
+	}
+	status = http.StatusInternalServerError
+	_, err = provider.Rate(context.Background(), "USD")
+	if err == nil{
+		t.Error("rate was read from a failed response")
+	}
+}
+
+func TestRateRefresherAndHistoricalRates(t *testing.T){
+	cash := &rateCash{}
+	refresher, err := NewRateRefresher(cash, NewFixedRateProvider(map[string]float64{"USD": 0.0125, "EUR": 0.01}), time.Hour)
+	if err != nil{
+		t.Fatal(err)
+	}
+	err = refresher.Refresh(context.Background())
+	if err != nil{
+		t.Fatal(err)
+	}
+	yesterday := time.Now().AddDate(0, 0, -1)
+	cash.tables[ratesKey(yesterday)] = map[string]string{"USD": "0.02"}
+	provider := &downRates{}
+	svc := NewService(&balanceDb{}, cash, provider)
+
+	resp, err := svc.GetBalance(context.Background(), &m.GetBalanceReq{UserId: 1, Currency: "EUR"})
+	if err != nil{
+		t.Fatal(err)
+	}
+	if resp.Balance != 100 || provider.calls != 0{
+		t.Errorf("rate table wasn't used: balance %d, %d calls to rates", resp.Balance, provider.calls)
+	}
+	resp, err = svc.GetBalance(context.Background(), &m.GetBalanceReq{UserId: 1, Currency: "USD", RateDate: yesterday})
+	if err != nil{
+		t.Fatal(err)
+	}
+	if resp.Balance != 200{
+		t.Errorf("balance wasn't converted at the historical rate: %d", resp.Balance)
+	}
+	_, err = svc.GetBalance(context.Background(), &m.GetBalanceReq{UserId: 1, Currency: "EUR", RateDate: yesterday})
+	if err == nil{
+		t.Error("balance was converted without a rate of the day")
+	}
+}
+
+func TestGetTransactionsConverted(t *testing.T){
+	cash := &rateCash{tables: map[string]map[string]string{}}
+	yesterday := time.Now().AddDate(0, 0, -1)
+	cash.tables[ratesKey(yesterday)] = map[string]string{"USD": "0.02"}
+	cash.tables[ratesKey(time.Now())] = map[string]string{"USD": "0.0125"}
+	db := &pageDb{trs: &m.Transactions{Transactions: []m.Transaction{
+		{TransId: 1, Change: 10000, Currency: "RUB", ChangeTime: yesterday},
+		{TransId: 2, Change: 10000, Currency: "RUB", ChangeTime: time.Now()},
+		{TransId: 3, Change: 10000, Currency: "RUB", ChangeTime: yesterday.AddDate(0, 0, -1)},
+		{TransId: 4, Change: 5000, Currency: "RUB", ChangeTime: yesterday},
+		{TransId: 5, Change: 5000, Currency: "RUB", ChangeTime: yesterday.AddDate(0, 0, -1)},
+	}}}
+	svc := NewService(db, cash, &downRates{})
+	resp, err := svc.GetTransactions(context.Background(), &m.GetTransactionsReq{UserId: 1, Currency: "USD"})
+	if err != nil{
+		t.Fatal(err)
+	}
+	trs := resp.Transactions
+	if trs[0].Converted == nil || *trs[0].Converted != 200 || trs[1].Converted == nil || *trs[1].Converted != 125{
+		t.Errorf("transactions weren't converted at the rates of their days: %v, %v", trs[0].Converted, trs[1].Converted)
+	}
+	if trs[2].Converted != nil || trs[4].Converted != nil{
+		t.Errorf("transaction was converted without a rate of its day")
+	}
+	if trs[3].Converted == nil || *trs[3].Converted != 100{
+		t.Errorf("transaction wasn't converted at the rate of its day: %v", trs[3].Converted)
+	}
+	// one lookup for each of the three days
+	if cash.lookups != 3{
+		t.Errorf("unexpected number of rate lookups: %d", cash.lookups)
+	}
+}
+
+func TestHTTPRateProviderCancel(t *testing.T){
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := NewHTTPRateProvider(ts.URL + "/?symbols=", time.Minute).Rate(ctx, "USD")
+	if !errors.Is(err, context.DeadlineExceeded){
+		t.Errorf("request wasn't cancelled with its context: %v", err)
+	}
+}
+
+type reconcileDb struct{
+	repair bool
+}
+
+func (d *reconcileDb) Reconcile(ctx context.Context, repair bool) (Resp *m.ReconciliationReport, err error){
+	d.repair = repair
+	transId := 7
+	return &m.ReconciliationReport{
+		Started: time.Date(2020, 10, 1, 3, 0, 0, 0, time.UTC),
+		Finished: time.Date(2020, 10, 1, 3, 0, 1, 0, time.UTC),
+		Repair: repair,
+		Wallets: 2,
+		Discrepancies: []m.Discrepancy{
+			{UserId: 1, Currency: "RUB", Kind: m.DiscrepancyChain, TransId: &transId, Expected: 10000, Actual: 12000},
+		},
+	}, nil
+}
+
+func TestReconcilerSavesReport(t *testing.T){
+	db := &reconcileDb{}
+	dir := filepath.Join(t.TempDir(), "reports")
+	r, err := NewReconciler(db, time.Hour, true, dir)
+	if err != nil{
+		t.Fatal(err)
+	}
+	_, path, err := r.Reconcile(context.Background())
+	if err != nil{
+		t.Fatal(err)
+	}
+	if !db.repair{
+		t.Error("reconciliation didn't run in repair mode")
+	}
+	if path != filepath.Join(dir, "reconcile-20201001T030000Z.json"){
+		t.Errorf("unexpected report path %s", path)
+	}
+	body, err := ioutil.ReadFile(path)
+	if err != nil{
+		t.Fatal(err)
+	}
+	expected := `{"started":"2020-10-01T03:00:00Z","finished":"2020-10-01T03:00:01Z","repair":true,"wallets":2,` +
+		`"discrepancies":[{"user_id":1,"currency":"RUB","kind":"broken_chain","trans_id":7,"expected":100,"actual":120,"repaired":false}]}`
+	if string(body) != expected{
+		t.Errorf("unexpected report:\n%s\nexpected:\n%s", body, expected)
+	}
+	if _, err = NewReconciler(db, 0, false, dir); err == nil{
+		t.Error("expected an error for a zero period")
+	}
+}
+
+// balancesDb serves wallets with 100 rubles for users 1 and 2 and records the request.
+type balancesDb struct{
+	dbClient
+	req *m.GetBalancesReq
+}
+
+func (d *balancesDb) SelectBalances(ctx context.Context, Req *m.GetBalancesReq) (Resp *m.GetBalancesResp, err error){
+	d.req = Req
+	Resp = &m.GetBalancesResp{AsOf: Req.AsOf, Currency: Req.Wallet, Missing: []int{}}
+	for _, id := range Req.UserIds{
+		if id > 2{
+			Resp.Missing = append(Resp.Missing, id)
+			continue
+		}
+		Resp.Balances = append(Resp.Balances, m.GetBalanceResp{UserId: id, Balance: 10000, Available: 10000, Currency: Req.Wallet})
+	}
+	return
+}
+
+func TestGetBalancesAsOf(t *testing.T){
+	cash := &rateCash{}
+	monthEnd := time.Now().AddDate(0, 0, -3)
+	cash.tables = map[string]map[string]string{ratesKey(monthEnd): {"USD": "0.02"}}
+	db := &balancesDb{}
+	svc := NewService(db, cash, &downRates{})
+
+	resp, err := svc.GetBalances(context.Background(), &m.GetBalancesReq{UserIds: []int{1, 2, 3}, Currency: "USD", AsOf: monthEnd})
+	if err != nil{
+		t.Fatal(err)
+	}
+	if db.req.Wallet != m.BaseCurrency{
+		t.Errorf("unexpected wallet %s", db.req.Wallet)
+	}
+	if resp.Currency != "USD" || len(resp.Balances) != 2 || !reflect.DeepEqual(resp.Missing, []int{3}){
+		t.Fatalf("unexpected balances: %+v", resp)
+	}
+	for _, b := range resp.Balances{
+		if b.Balance != 200 || b.Available != 200 || b.Currency != "USD"{
+			t.Errorf("balance wasn't converted at the rate of the as of day: %+v", b)
+		}
+	}
+
+	_, err = svc.GetBalances(context.Background(), &m.GetBalancesReq{UserIds: []int{1}})
+	if err != nil{
+		t.Fatal(err)
+	}
+	if db.req.AsOf.IsZero() || time.Since(db.req.AsOf) > time.Minute{
+		t.Errorf("as of doesn't default to now: %v", db.req.AsOf)
+	}
+	_, err = svc.GetBalances(context.Background(), &m.GetBalancesReq{UserIds: []int{1}, AsOf: time.Now().Add(time.Hour)})
+	if m.KindOf(err) != m.KindValidation{
+		t.Errorf("unexpected error for a future as of: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fedorkolmykow/avitojob/pkg/config"
+)
+
+const (
+	serviceName = "jobber"
+	instrumentation = "github.com/fedorkolmykow/avitojob"
+)
+
+// Start starts a span of the service. Spans go to the provider set by Setup,
+// until then and with the "none" exporter they aren't recorded.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span){
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+// End marks span failed when *err is set and ends it. It is deferred with the named error of a function.
+func End(span trace.Span, err *error){
+	if err != nil && *err != nil{
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// Setup installs the exporter of cfg and the W3C trace context propagator. The returned
+// function flushes the spans left and closes the exporter.
+func Setup(ctx context.Context, cfg config.Tracing) (shutdown func(context.Context) error, err error){
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	var exporter sdktrace.SpanExporter
+	var file io.Closer
+	switch cfg.Exporter{
+	case "none":
+		return func(context.Context) error{ return nil }, nil
+	case "stdout":
+		var out io.Writer = os.Stdout
+		if cfg.File != ""{
+			f, e := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+			if e != nil{
+				return nil, e
+			}
+			out, file = f, f
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure{
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, errors.New("unknown trace exporter " + cfg.Exporter)
+	}
+	if err != nil{
+		return
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error{
+		err := provider.Shutdown(ctx)
+		if file != nil{
+			if e := file.Close(); err == nil{
+				err = e
+			}
+		}
+		return err
+	}, nil
+}